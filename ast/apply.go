@@ -0,0 +1,241 @@
+package ast
+
+import "fmt"
+
+// An ApplyFunc is invoked by Apply for each node before and/or after the
+// node's children, using a Cursor describing the current node and providing
+// operations on it. Absent optional nodes, such as the Init of a Def
+// without one, are skipped.
+//
+// The return value of ApplyFunc controls the syntax tree traversal. See
+// Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses the tree rooted at root recursively, starting with root,
+// and calling pre and post for each node as described below. Apply returns
+// the tree, possibly modified.
+//
+// If pre is not nil, it is called for each node before the node's children
+// are traversed (pre-order). If pre returns false, no children are
+// traversed, and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false, post is
+// called for each node after its children are traversed (post-order). If
+// post returns false, traversal is terminated and Apply returns
+// immediately.
+//
+// Only fields that refer to nodes are traversed, in source order. Nodes
+// inserted by Cursor.InsertBefore and Cursor.InsertAfter are not
+// traversed; a node set by Cursor.Replace in pre is traversed in place of
+// the old one.
+func Apply(root Node, pre ApplyFunc, post ApplyFunc) (result Node) {
+	a := &application{pre: pre, post: post}
+	defer func() {
+		if r := recover(); r != nil && r != abort {
+			panic(r)
+		}
+		result = root
+	}()
+	a.apply(Cursor{name: "Root", node: root, index: -1, set: func(n Node) { root = n }})
+	return root
+}
+
+var abort = new(int)
+
+// A Cursor describes a node encountered during Apply. Information about the
+// node and its parent is available from the Node, Parent, Name and Index
+// methods.
+type Cursor struct {
+	parent Node
+	name   string
+	node   Node
+	index  int
+	set    func(Node)
+	list   *[]Statement
+	iter   *iterator
+}
+
+// iterator keeps the position in a statement list under modification.
+type iterator struct {
+	index int
+	step  int
+}
+
+// Node returns the current node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current node.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent field containing the current node,
+// as Dump labels it. The root is named "Root".
+func (c *Cursor) Name() string { return c.name }
+
+// Index reports the index of the current node in the slice of the parent
+// field that contains it, or a value < 0 if it is not part of a slice.
+func (c *Cursor) Index() int { return c.index }
+
+// Replace replaces the current node with n. The replacement must fit the
+// field, e.g. a Statement in a statement list; otherwise Replace panics.
+func (c *Cursor) Replace(n Node) {
+	c.set(n)
+	c.node = n
+}
+
+// Delete deletes the current node from its containing statement list. If
+// the current node is not part of one, Delete panics.
+func (c *Cursor) Delete() {
+	list := c.statementList("Delete")
+	i := c.iter.index
+	*list = append((*list)[:i], (*list)[i+1:]...)
+	c.iter.step--
+}
+
+// InsertAfter inserts s after the current node in its containing statement
+// list. If the current node is not part of one, InsertAfter panics.
+func (c *Cursor) InsertAfter(s Statement) {
+	list := c.statementList("InsertAfter")
+	i := c.iter.index
+	*list = append((*list)[:i+1], append([]Statement{s}, (*list)[i+1:]...)...)
+	c.iter.step++
+}
+
+// InsertBefore inserts s before the current node in its containing
+// statement list. If the current node is not part of one, InsertBefore
+// panics.
+func (c *Cursor) InsertBefore(s Statement) {
+	list := c.statementList("InsertBefore")
+	i := c.iter.index
+	*list = append((*list)[:i], append([]Statement{s}, (*list)[i:]...)...)
+	c.iter.index++
+	c.index++
+}
+
+func (c *Cursor) statementList(op string) *[]Statement {
+	if c.list == nil {
+		panic(fmt.Sprintf("ast.Cursor.%s: %s of %T is not a statement list", op, c.name, c.parent))
+	}
+	return c.list
+}
+
+type application struct {
+	pre  ApplyFunc
+	post ApplyFunc
+}
+
+func (a *application) apply(c Cursor) {
+	if a.pre != nil && !a.pre(&c) {
+		return
+	}
+	if c.node != nil {
+		a.children(c.node)
+	}
+	if a.post != nil && !a.post(&c) {
+		panic(abort)
+	}
+}
+
+func (a *application) field(parent Node, name string, n Node, set func(Node)) {
+	a.apply(Cursor{parent: parent, name: name, node: n, index: -1, set: set})
+}
+
+func (a *application) expression(parent Node, name string, x *Expression) {
+	if *x == nil {
+		return
+	}
+	a.field(parent, name, *x, func(n Node) { *x = n.(Expression) })
+}
+
+func (a *application) expressions(parent Node, name string, xs []Expression) {
+	for i := range xs {
+		a.apply(Cursor{parent: parent, name: name, node: xs[i], index: i, set: func(n Node) { xs[i] = n.(Expression) }})
+	}
+}
+
+func (a *application) statements(parent Node, name string, list *[]Statement) {
+	iter := &iterator{}
+	for iter.index < len(*list) {
+		iter.step = 1
+		i := iter.index
+		a.apply(Cursor{
+			parent: parent,
+			name:   name,
+			node:   (*list)[i],
+			index:  i,
+			set:    func(n Node) { (*list)[iter.index] = n.(Statement) },
+			list:   list,
+			iter:   iter,
+		})
+		iter.index += iter.step
+	}
+}
+
+func (a *application) children(node Node) {
+	switch n := node.(type) {
+	case *Program:
+		a.statements(n, "Statements", &n.Statements)
+	case *InvalidStatement, *InvalidExpression, *Break, *Continue, *Comment,
+		*Identifier, *NilLiteral, *BoolLiteral, *IntLiteral, *FloatLiteral, *StringLiteral:
+		// leaves
+	case *Def:
+		a.field(n, "Name", n.Name, func(x Node) { n.Name = x.(*Identifier) })
+		a.expression(n, "Init", &n.Init)
+	case *While:
+		a.expression(n, "Cond", &n.Cond)
+		a.statements(n, "Body", &n.Body)
+	case *For:
+		for i := range n.Vars {
+			a.apply(Cursor{parent: n, name: "Vars", node: n.Vars[i], index: i, set: func(x Node) { n.Vars[i] = x.(*Identifier) }})
+		}
+		a.expression(n, "Iterable", &n.Iterable)
+		a.statements(n, "Body", &n.Body)
+	case *Return:
+		a.expression(n, "Expression", &n.Expression)
+	case *If:
+		a.expression(n, "Test", &n.Test)
+		a.statements(n, "Body", &n.Body)
+		a.expression(n, "Alt", &n.Alt)
+	case *Else:
+		a.statements(n, "Body", &n.Body)
+	case *ExpressionStatement:
+		a.expression(n, "Expression", &n.Expression)
+	case *PrefixExpression:
+		a.expression(n, "Right", &n.Right)
+	case *InterpolatedString:
+		a.expressions(n, "Parts", n.Parts)
+	case *ArrayLiteral:
+		a.expressions(n, "Elements", n.Elements)
+	case *HashLiteral:
+		for i := range n.Pairs {
+			a.apply(Cursor{parent: n, name: "Pairs", node: n.Pairs[i], index: i, set: func(x Node) { n.Pairs[i] = x.(*HashEntry) }})
+		}
+	case *HashEntry:
+		a.expression(n, "Key", &n.Key)
+		a.expression(n, "Value", &n.Value)
+	case *FunctionLiteral:
+		for i := range n.Parameters {
+			a.apply(Cursor{parent: n, name: "Parameters", node: n.Parameters[i], index: i, set: func(x Node) { n.Parameters[i] = x.(*Identifier) }})
+		}
+		a.statements(n, "Statements", &n.Statements)
+	case *InfixExpression:
+		a.expression(n, "Left", &n.Left)
+		a.expression(n, "Right", &n.Right)
+	case *LogicalExpression:
+		a.expression(n, "Left", &n.Left)
+		a.expression(n, "Right", &n.Right)
+	case *Call:
+		a.expression(n, "Function", &n.Function)
+		a.expressions(n, "Arguments", n.Arguments)
+	case *KeyAccess:
+		a.expression(n, "Container", &n.Container)
+		a.expression(n, "Key", &n.Key)
+	case *Let:
+		a.field(n, "Left", n.Left, func(x Node) { n.Left = x.(*Identifier) })
+		a.expression(n, "Right", &n.Right)
+	case *KeyAssign:
+		a.field(n, "Left", n.Left, func(x Node) { n.Left = x.(*KeyAccess) })
+		a.expression(n, "Right", &n.Right)
+	default:
+		panic(fmt.Sprintf("ast.Apply: unexpected node type %T", n))
+	}
+}
@@ -0,0 +1,73 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DumpDot writes tree as a Graphviz digraph. Each node is labeled with its
+// type, its value if it has one, and its location; each edge with the
+// field of the parent holding the child, as Dump names it, and the index
+// for list fields. A node shared by two fields, like the target of a
+// compound assignment, is drawn once.
+func DumpDot(tree Node, dest io.Writer) {
+	var b strings.Builder
+	b.WriteString("digraph AST {\n")
+	b.WriteString("\tnode [shape=box fontname=monospace];\n")
+	ids := map[Node]int{}
+	Apply(tree, func(c *Cursor) bool {
+		n := c.Node()
+		id, seen := ids[n]
+		if !seen {
+			id = len(ids)
+			ids[n] = id
+			fmt.Fprintf(&b, "\tn%d [label=%s];\n", id, strconv.Quote(dotLabel(n)))
+		}
+		if p := c.Parent(); p != nil {
+			label := c.Name()
+			if c.Index() >= 0 {
+				label = fmt.Sprintf("%s[%d]", label, c.Index())
+			}
+			fmt.Fprintf(&b, "\tn%d -> n%d [label=%s];\n", ids[p], id, strconv.Quote(label))
+		}
+		return !seen
+	}, nil)
+	b.WriteString("}\n")
+	io.WriteString(dest, b.String())
+}
+
+func dotLabel(node Node) string {
+	label := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	switch n := node.(type) {
+	case *Program:
+		label += " " + n.FileName
+	case *Comment:
+		label += " " + n.Text
+	case *InvalidStatement:
+		label += " " + n.Err.Error()
+	case *InvalidExpression:
+		label += " " + n.Err.Error()
+	case *Identifier:
+		label += " " + n.Name
+	case *BoolLiteral:
+		label += " " + strconv.FormatBool(n.Value)
+	case *IntLiteral:
+		label += " " + strconv.Itoa(n.Value)
+	case *FloatLiteral:
+		label += " " + strconv.FormatFloat(n.Value, 'g', -1, 64)
+	case *StringLiteral:
+		label += " " + strconv.Quote(n.Value)
+	case *PrefixExpression:
+		label += " " + n.Operator.String()
+	case *InfixExpression:
+		label += " " + n.Operator.String()
+	case *LogicalExpression:
+		label += " " + n.Operator.String()
+	}
+	if loc := node.Location(); loc != nil {
+		label += "\n" + loc.String()
+	}
+	return label
+}
@@ -0,0 +1,32 @@
+package ast_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/arikui1911/goore/ast"
+)
+
+func TestDumpDot(t *testing.T) {
+	var buf bytes.Buffer
+	ast.DumpDot(parse(t, "x += 1"), &buf)
+	want := `digraph AST {
+	node [shape=box fontname=monospace];
+	n0 [label="Program test.goore\n(1:1):(1:7)"];
+	n1 [label="ExpressionStatement\n(1:1):(1:7)"];
+	n0 -> n1 [label="Statements[0]"];
+	n2 [label="Let\n(1:1):(1:6)"];
+	n1 -> n2 [label="Expression"];
+	n3 [label="Identifier x\n(1:1):(1:1)"];
+	n2 -> n3 [label="Left"];
+	n4 [label="InfixExpression Add\n(1:1):(1:6)"];
+	n2 -> n4 [label="Right"];
+	n4 -> n3 [label="Left"];
+	n5 [label="IntLiteral 1\n(1:6):(1:6)"];
+	n4 -> n5 [label="Right"];
+}
+`
+	if buf.String() != want {
+		t.Errorf("want <%v> got <%v>", want, buf.String())
+	}
+}
@@ -0,0 +1,896 @@
+package ast
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/token"
+)
+
+// Every node marshals to a JSON object with its type name under "type",
+// its location under "loc" and its fields in camel case. Nodes in
+// interface typed fields are decoded by their "type". The comments of a
+// Program are not serialized.
+
+type jsonHeader struct {
+	Type string          `json:"type"`
+	Loc  *token.Location `json:"loc"`
+}
+
+var nodeTypes map[string]func() Node
+
+func init() {
+	nodeTypes = map[string]func() Node{
+		"Program":             func() Node { return &Program{} },
+		"Comment":             func() Node { return &Comment{} },
+		"InvalidStatement":    func() Node { return &InvalidStatement{} },
+		"InvalidExpression":   func() Node { return &InvalidExpression{} },
+		"Def":                 func() Node { return &Def{} },
+		"While":               func() Node { return &While{} },
+		"For":                 func() Node { return &For{} },
+		"Break":               func() Node { return &Break{} },
+		"Continue":            func() Node { return &Continue{} },
+		"Return":              func() Node { return &Return{} },
+		"If":                  func() Node { return &If{} },
+		"Else":                func() Node { return &Else{} },
+		"ExpressionStatement": func() Node { return &ExpressionStatement{} },
+		"Identifier":          func() Node { return &Identifier{} },
+		"NilLiteral":          func() Node { return &NilLiteral{} },
+		"BoolLiteral":         func() Node { return &BoolLiteral{} },
+		"IntLiteral":          func() Node { return &IntLiteral{} },
+		"FloatLiteral":        func() Node { return &FloatLiteral{} },
+		"StringLiteral":       func() Node { return &StringLiteral{} },
+		"InterpolatedString":  func() Node { return &InterpolatedString{} },
+		"PrefixExpression":    func() Node { return &PrefixExpression{} },
+		"ArrayLiteral":        func() Node { return &ArrayLiteral{} },
+		"HashLiteral":         func() Node { return &HashLiteral{} },
+		"HashEntry":           func() Node { return &HashEntry{} },
+		"FunctionLiteral":     func() Node { return &FunctionLiteral{} },
+		"InfixExpression":     func() Node { return &InfixExpression{} },
+		"LogicalExpression":   func() Node { return &LogicalExpression{} },
+		"Call":                func() Node { return &Call{} },
+		"KeyAccess":           func() Node { return &KeyAccess{} },
+		"Let":                 func() Node { return &Let{} },
+		"KeyAssign":           func() Node { return &KeyAssign{} },
+	}
+}
+
+// UnmarshalNode decodes a node of any type from JSON.
+func UnmarshalNode(data []byte) (Node, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var h jsonHeader
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, err
+	}
+	mk, ok := nodeTypes[h.Type]
+	if !ok {
+		return nil, fmt.Errorf("ast: unknown node type %q", h.Type)
+	}
+	n := mk()
+	if err := json.Unmarshal(data, n); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func unmarshalHeader(data []byte, typ string, v any, h *jsonHeader) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return err
+	}
+	if h.Type != typ {
+		return fmt.Errorf("ast: want node type %s got %q", typ, h.Type)
+	}
+	return nil
+}
+
+func unmarshalExpression(data json.RawMessage) (Expression, error) {
+	n, err := UnmarshalNode(data)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	x, ok := n.(Expression)
+	if !ok {
+		return nil, fmt.Errorf("ast: %T is not an expression", n)
+	}
+	return x, nil
+}
+
+func unmarshalStatement(data json.RawMessage) (Statement, error) {
+	n, err := UnmarshalNode(data)
+	if err != nil || n == nil {
+		return nil, err
+	}
+	s, ok := n.(Statement)
+	if !ok {
+		return nil, fmt.Errorf("ast: %T is not a statement", n)
+	}
+	return s, nil
+}
+
+func unmarshalExpressions(data []json.RawMessage) ([]Expression, error) {
+	if data == nil {
+		return nil, nil
+	}
+	xs := make([]Expression, len(data))
+	for i, d := range data {
+		x, err := unmarshalExpression(d)
+		if err != nil {
+			return nil, err
+		}
+		xs[i] = x
+	}
+	return xs, nil
+}
+
+func unmarshalStatements(data []json.RawMessage) ([]Statement, error) {
+	if data == nil {
+		return nil, nil
+	}
+	stmts := make([]Statement, len(data))
+	for i, d := range data {
+		s, err := unmarshalStatement(d)
+		if err != nil {
+			return nil, err
+		}
+		stmts[i] = s
+	}
+	return stmts, nil
+}
+
+func (op Operation) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+func (op *Operation) UnmarshalText(text []byte) error {
+	for i := Operation(0); int(i) < len(_Operation_index)-1; i++ {
+		if i.String() == string(text) {
+			*op = i
+			return nil
+		}
+	}
+	return fmt.Errorf("ast: unknown operation %q", text)
+}
+
+func (n *Program) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		FileName    string      `json:"fileName"`
+		Statements  []Statement `json:"statements"`
+		Diagnostics diag.List   `json:"diagnostics,omitempty"`
+	}{jsonHeader{"Program", n.Loc}, n.FileName, n.Statements, n.Diagnostics})
+}
+
+func (n *Program) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		FileName    string            `json:"fileName"`
+		Statements  []json.RawMessage `json:"statements"`
+		Diagnostics diag.List         `json:"diagnostics"`
+	}
+	if err := unmarshalHeader(data, "Program", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	stmts, err := unmarshalStatements(v.Statements)
+	if err != nil {
+		return err
+	}
+	*n = Program{Loc: v.Loc, FileName: v.FileName, Statements: stmts, Diagnostics: v.Diagnostics, Err: v.Diagnostics.Err()}
+	return nil
+}
+
+func (n *Comment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Text string `json:"text"`
+	}{jsonHeader{"Comment", n.Loc}, n.Text})
+}
+
+func (n *Comment) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Text string `json:"text"`
+	}
+	if err := unmarshalHeader(data, "Comment", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = Comment{Loc: v.Loc, Text: v.Text}
+	return nil
+}
+
+// Invalid nodes keep their error message, and the diagnostic itself when
+// it is one.
+func marshalInvalid(typ string, loc *token.Location, err error) ([]byte, error) {
+	var d *diag.Diagnostic
+	errors.As(err, &d)
+	return json.Marshal(struct {
+		jsonHeader
+		Error      string           `json:"error"`
+		Diagnostic *diag.Diagnostic `json:"diagnostic,omitempty"`
+	}{jsonHeader{typ, loc}, err.Error(), d})
+}
+
+func unmarshalInvalid(data []byte, typ string) (*token.Location, error, error) {
+	var v struct {
+		jsonHeader
+		Error      string           `json:"error"`
+		Diagnostic *diag.Diagnostic `json:"diagnostic"`
+	}
+	if err := unmarshalHeader(data, typ, &v, &v.jsonHeader); err != nil {
+		return nil, nil, err
+	}
+	if v.Diagnostic == nil {
+		return v.Loc, errors.New(v.Error), nil
+	}
+	return v.Loc, v.Diagnostic, nil
+}
+
+func (n *InvalidStatement) MarshalJSON() ([]byte, error) {
+	return marshalInvalid("InvalidStatement", n.Loc, n.Err)
+}
+
+func (n *InvalidStatement) UnmarshalJSON(data []byte) error {
+	loc, cause, err := unmarshalInvalid(data, "InvalidStatement")
+	if err != nil {
+		return err
+	}
+	*n = InvalidStatement{Loc: loc, Err: cause}
+	return nil
+}
+
+func (n *InvalidExpression) MarshalJSON() ([]byte, error) {
+	return marshalInvalid("InvalidExpression", n.Loc, n.Err)
+}
+
+func (n *InvalidExpression) UnmarshalJSON(data []byte) error {
+	loc, cause, err := unmarshalInvalid(data, "InvalidExpression")
+	if err != nil {
+		return err
+	}
+	*n = InvalidExpression{Loc: loc, Err: cause}
+	return nil
+}
+
+func (n *Def) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Name *Identifier `json:"name"`
+		Init Expression  `json:"init,omitempty"`
+	}{jsonHeader{"Def", n.Loc}, n.Name, n.Init})
+}
+
+func (n *Def) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Name *Identifier     `json:"name"`
+		Init json.RawMessage `json:"init"`
+	}
+	if err := unmarshalHeader(data, "Def", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	init, err := unmarshalExpression(v.Init)
+	if err != nil {
+		return err
+	}
+	*n = Def{Loc: v.Loc, Name: v.Name, Init: init}
+	return nil
+}
+
+func (n *While) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Cond Expression  `json:"cond"`
+		Body []Statement `json:"body"`
+	}{jsonHeader{"While", n.Loc}, n.Cond, n.Body})
+}
+
+func (n *While) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Cond json.RawMessage   `json:"cond"`
+		Body []json.RawMessage `json:"body"`
+	}
+	if err := unmarshalHeader(data, "While", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	cond, err := unmarshalExpression(v.Cond)
+	if err != nil {
+		return err
+	}
+	body, err := unmarshalStatements(v.Body)
+	if err != nil {
+		return err
+	}
+	*n = While{Loc: v.Loc, Cond: cond, Body: body}
+	return nil
+}
+
+func (n *For) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Vars     []*Identifier `json:"vars"`
+		Iterable Expression    `json:"iterable"`
+		Body     []Statement   `json:"body"`
+	}{jsonHeader{"For", n.Loc}, n.Vars, n.Iterable, n.Body})
+}
+
+func (n *For) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Vars     []*Identifier     `json:"vars"`
+		Iterable json.RawMessage   `json:"iterable"`
+		Body     []json.RawMessage `json:"body"`
+	}
+	if err := unmarshalHeader(data, "For", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	iterable, err := unmarshalExpression(v.Iterable)
+	if err != nil {
+		return err
+	}
+	body, err := unmarshalStatements(v.Body)
+	if err != nil {
+		return err
+	}
+	*n = For{Loc: v.Loc, Vars: v.Vars, Iterable: iterable, Body: body}
+	return nil
+}
+
+func (n *Break) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonHeader{"Break", n.Loc})
+}
+
+func (n *Break) UnmarshalJSON(data []byte) error {
+	var v jsonHeader
+	if err := unmarshalHeader(data, "Break", &v, &v); err != nil {
+		return err
+	}
+	*n = Break{Loc: v.Loc}
+	return nil
+}
+
+func (n *Continue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonHeader{"Continue", n.Loc})
+}
+
+func (n *Continue) UnmarshalJSON(data []byte) error {
+	var v jsonHeader
+	if err := unmarshalHeader(data, "Continue", &v, &v); err != nil {
+		return err
+	}
+	*n = Continue{Loc: v.Loc}
+	return nil
+}
+
+func (n *Return) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Expression Expression `json:"expression,omitempty"`
+	}{jsonHeader{"Return", n.Loc}, n.Expression})
+}
+
+func (n *Return) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Expression json.RawMessage `json:"expression"`
+	}
+	if err := unmarshalHeader(data, "Return", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	x, err := unmarshalExpression(v.Expression)
+	if err != nil {
+		return err
+	}
+	*n = Return{Loc: v.Loc, Expression: x}
+	return nil
+}
+
+func (n *If) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Test Expression  `json:"test"`
+		Body []Statement `json:"body"`
+		Alt  Expression  `json:"alt,omitempty"`
+	}{jsonHeader{"If", n.Loc}, n.Test, n.Body, n.Alt})
+}
+
+func (n *If) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Test json.RawMessage   `json:"test"`
+		Body []json.RawMessage `json:"body"`
+		Alt  json.RawMessage   `json:"alt"`
+	}
+	if err := unmarshalHeader(data, "If", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	test, err := unmarshalExpression(v.Test)
+	if err != nil {
+		return err
+	}
+	body, err := unmarshalStatements(v.Body)
+	if err != nil {
+		return err
+	}
+	alt, err := unmarshalExpression(v.Alt)
+	if err != nil {
+		return err
+	}
+	*n = If{Loc: v.Loc, Test: test, Body: body, Alt: alt}
+	return nil
+}
+
+func (n *Else) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Body []Statement `json:"body"`
+	}{jsonHeader{"Else", n.Loc}, n.Body})
+}
+
+func (n *Else) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Body []json.RawMessage `json:"body"`
+	}
+	if err := unmarshalHeader(data, "Else", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	body, err := unmarshalStatements(v.Body)
+	if err != nil {
+		return err
+	}
+	*n = Else{Loc: v.Loc, Body: body}
+	return nil
+}
+
+func (n *ExpressionStatement) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Expression Expression `json:"expression"`
+	}{jsonHeader{"ExpressionStatement", n.Loc}, n.Expression})
+}
+
+func (n *ExpressionStatement) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Expression json.RawMessage `json:"expression"`
+	}
+	if err := unmarshalHeader(data, "ExpressionStatement", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	x, err := unmarshalExpression(v.Expression)
+	if err != nil {
+		return err
+	}
+	*n = ExpressionStatement{Loc: v.Loc, Expression: x}
+	return nil
+}
+
+func (n *Identifier) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Name string `json:"name"`
+	}{jsonHeader{"Identifier", n.Loc}, n.Name})
+}
+
+func (n *Identifier) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Name string `json:"name"`
+	}
+	if err := unmarshalHeader(data, "Identifier", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = Identifier{Loc: v.Loc, Name: v.Name}
+	return nil
+}
+
+func (n *NilLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonHeader{"NilLiteral", n.Loc})
+}
+
+func (n *NilLiteral) UnmarshalJSON(data []byte) error {
+	var v jsonHeader
+	if err := unmarshalHeader(data, "NilLiteral", &v, &v); err != nil {
+		return err
+	}
+	*n = NilLiteral{Loc: v.Loc}
+	return nil
+}
+
+func (n *BoolLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Value bool `json:"value"`
+	}{jsonHeader{"BoolLiteral", n.Loc}, n.Value})
+}
+
+func (n *BoolLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Value bool `json:"value"`
+	}
+	if err := unmarshalHeader(data, "BoolLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = BoolLiteral{Loc: v.Loc, Value: v.Value}
+	return nil
+}
+
+func (n *IntLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Value int    `json:"value"`
+		Raw   string `json:"raw,omitempty"`
+	}{jsonHeader{"IntLiteral", n.Loc}, n.Value, n.Raw})
+}
+
+func (n *IntLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Value int    `json:"value"`
+		Raw   string `json:"raw,omitempty"`
+	}
+	if err := unmarshalHeader(data, "IntLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = IntLiteral{Loc: v.Loc, Value: v.Value, Raw: v.Raw}
+	return nil
+}
+
+func (n *FloatLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Value float64 `json:"value"`
+		Raw   string  `json:"raw,omitempty"`
+	}{jsonHeader{"FloatLiteral", n.Loc}, n.Value, n.Raw})
+}
+
+func (n *FloatLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Value float64 `json:"value"`
+		Raw   string  `json:"raw,omitempty"`
+	}
+	if err := unmarshalHeader(data, "FloatLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = FloatLiteral{Loc: v.Loc, Value: v.Value, Raw: v.Raw}
+	return nil
+}
+
+func (n *StringLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Value string `json:"value"`
+	}{jsonHeader{"StringLiteral", n.Loc}, n.Value})
+}
+
+func (n *StringLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Value string `json:"value"`
+	}
+	if err := unmarshalHeader(data, "StringLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = StringLiteral{Loc: v.Loc, Value: v.Value}
+	return nil
+}
+
+func (n *InterpolatedString) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Parts []Expression `json:"parts"`
+	}{jsonHeader{"InterpolatedString", n.Loc}, n.Parts})
+}
+
+func (n *InterpolatedString) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := unmarshalHeader(data, "InterpolatedString", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	parts, err := unmarshalExpressions(v.Parts)
+	if err != nil {
+		return err
+	}
+	*n = InterpolatedString{Loc: v.Loc, Parts: parts}
+	return nil
+}
+
+func (n *PrefixExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Operator Operation  `json:"operator"`
+		Right    Expression `json:"right"`
+	}{jsonHeader{"PrefixExpression", n.Loc}, n.Operator, n.Right})
+}
+
+func (n *PrefixExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Operator Operation       `json:"operator"`
+		Right    json.RawMessage `json:"right"`
+	}
+	if err := unmarshalHeader(data, "PrefixExpression", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	right, err := unmarshalExpression(v.Right)
+	if err != nil {
+		return err
+	}
+	*n = PrefixExpression{Loc: v.Loc, Operator: v.Operator, Right: right}
+	return nil
+}
+
+func (n *ArrayLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Elements []Expression `json:"elements"`
+	}{jsonHeader{"ArrayLiteral", n.Loc}, n.Elements})
+}
+
+func (n *ArrayLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Elements []json.RawMessage `json:"elements"`
+	}
+	if err := unmarshalHeader(data, "ArrayLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	elems, err := unmarshalExpressions(v.Elements)
+	if err != nil {
+		return err
+	}
+	*n = ArrayLiteral{Loc: v.Loc, Elements: elems}
+	return nil
+}
+
+func (n *HashLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Pairs []*HashEntry `json:"pairs"`
+	}{jsonHeader{"HashLiteral", n.Loc}, n.Pairs})
+}
+
+func (n *HashLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Pairs []*HashEntry `json:"pairs"`
+	}
+	if err := unmarshalHeader(data, "HashLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	*n = HashLiteral{Loc: v.Loc, Pairs: v.Pairs}
+	return nil
+}
+
+func (n *HashEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Key   Expression `json:"key"`
+		Value Expression `json:"value"`
+	}{jsonHeader{"HashEntry", n.Loc}, n.Key, n.Value})
+}
+
+func (n *HashEntry) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Key   json.RawMessage `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := unmarshalHeader(data, "HashEntry", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	key, err := unmarshalExpression(v.Key)
+	if err != nil {
+		return err
+	}
+	value, err := unmarshalExpression(v.Value)
+	if err != nil {
+		return err
+	}
+	*n = HashEntry{Loc: v.Loc, Key: key, Value: value}
+	return nil
+}
+
+func (n *FunctionLiteral) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Parameters []*Identifier `json:"parameters"`
+		Statements []Statement   `json:"statements"`
+	}{jsonHeader{"FunctionLiteral", n.Loc}, n.Parameters, n.Statements})
+}
+
+func (n *FunctionLiteral) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Parameters []*Identifier     `json:"parameters"`
+		Statements []json.RawMessage `json:"statements"`
+	}
+	if err := unmarshalHeader(data, "FunctionLiteral", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	stmts, err := unmarshalStatements(v.Statements)
+	if err != nil {
+		return err
+	}
+	*n = FunctionLiteral{Loc: v.Loc, Parameters: v.Parameters, Statements: stmts}
+	return nil
+}
+
+func (n *InfixExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Operator Operation  `json:"operator"`
+		Left     Expression `json:"left"`
+		Right    Expression `json:"right"`
+	}{jsonHeader{"InfixExpression", n.Loc}, n.Operator, n.Left, n.Right})
+}
+
+func (n *InfixExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Operator Operation       `json:"operator"`
+		Left     json.RawMessage `json:"left"`
+		Right    json.RawMessage `json:"right"`
+	}
+	if err := unmarshalHeader(data, "InfixExpression", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	left, err := unmarshalExpression(v.Left)
+	if err != nil {
+		return err
+	}
+	right, err := unmarshalExpression(v.Right)
+	if err != nil {
+		return err
+	}
+	*n = InfixExpression{Loc: v.Loc, Operator: v.Operator, Left: left, Right: right}
+	return nil
+}
+
+func (n *LogicalExpression) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Operator Operation  `json:"operator"`
+		Left     Expression `json:"left"`
+		Right    Expression `json:"right"`
+	}{jsonHeader{"LogicalExpression", n.Loc}, n.Operator, n.Left, n.Right})
+}
+
+func (n *LogicalExpression) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Operator Operation       `json:"operator"`
+		Left     json.RawMessage `json:"left"`
+		Right    json.RawMessage `json:"right"`
+	}
+	if err := unmarshalHeader(data, "LogicalExpression", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	left, err := unmarshalExpression(v.Left)
+	if err != nil {
+		return err
+	}
+	right, err := unmarshalExpression(v.Right)
+	if err != nil {
+		return err
+	}
+	*n = LogicalExpression{Loc: v.Loc, Operator: v.Operator, Left: left, Right: right}
+	return nil
+}
+
+func (n *Call) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Function  Expression   `json:"function"`
+		Arguments []Expression `json:"arguments"`
+	}{jsonHeader{"Call", n.Loc}, n.Function, n.Arguments})
+}
+
+func (n *Call) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Function  json.RawMessage   `json:"function"`
+		Arguments []json.RawMessage `json:"arguments"`
+	}
+	if err := unmarshalHeader(data, "Call", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	fn, err := unmarshalExpression(v.Function)
+	if err != nil {
+		return err
+	}
+	args, err := unmarshalExpressions(v.Arguments)
+	if err != nil {
+		return err
+	}
+	*n = Call{Loc: v.Loc, Function: fn, Arguments: args}
+	return nil
+}
+
+func (n *KeyAccess) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Container Expression `json:"container"`
+		Key       Expression `json:"key"`
+	}{jsonHeader{"KeyAccess", n.Loc}, n.Container, n.Key})
+}
+
+func (n *KeyAccess) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Container json.RawMessage `json:"container"`
+		Key       json.RawMessage `json:"key"`
+	}
+	if err := unmarshalHeader(data, "KeyAccess", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	container, err := unmarshalExpression(v.Container)
+	if err != nil {
+		return err
+	}
+	key, err := unmarshalExpression(v.Key)
+	if err != nil {
+		return err
+	}
+	*n = KeyAccess{Loc: v.Loc, Container: container, Key: key}
+	return nil
+}
+
+func (n *Let) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Left  *Identifier `json:"left"`
+		Right Expression  `json:"right"`
+	}{jsonHeader{"Let", n.Loc}, n.Left, n.Right})
+}
+
+func (n *Let) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Left  *Identifier     `json:"left"`
+		Right json.RawMessage `json:"right"`
+	}
+	if err := unmarshalHeader(data, "Let", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	right, err := unmarshalExpression(v.Right)
+	if err != nil {
+		return err
+	}
+	*n = Let{Loc: v.Loc, Left: v.Left, Right: right}
+	return nil
+}
+
+func (n *KeyAssign) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		jsonHeader
+		Left  *KeyAccess `json:"left"`
+		Right Expression `json:"right"`
+	}{jsonHeader{"KeyAssign", n.Loc}, n.Left, n.Right})
+}
+
+func (n *KeyAssign) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonHeader
+		Left  *KeyAccess      `json:"left"`
+		Right json.RawMessage `json:"right"`
+	}
+	if err := unmarshalHeader(data, "KeyAssign", &v, &v.jsonHeader); err != nil {
+		return err
+	}
+	right, err := unmarshalExpression(v.Right)
+	if err != nil {
+		return err
+	}
+	*n = KeyAssign{Loc: v.Loc, Left: v.Left, Right: right}
+	return nil
+}
@@ -0,0 +1,105 @@
+package ast_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/parser"
+)
+
+func dump(n ast.Node) string {
+	var buf bytes.Buffer
+	ast.Dump(n, &buf)
+	return buf.String()
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	table := []string{
+		"def f = -> (a, b) { return a * b }\nf(1, 2.5)",
+		"def x\nx = nil\nx += 1",
+		"while !done { if x < 1 { break } else if y { continue } else { return } }",
+		"h = {\"k\": [1, true, -x]}\nh[\"k\"][0] = \"s\\n\"",
+		"def 1\nx",
+		"a && b || c",
+		"\"a #{b + \"c#{d}\"} e\"",
+		"for i, x in range(3) { puts(i, x) }\nfor c in \"ab\" { continue }",
+		"[0xFF, 1_000, 2.5e-3]",
+	}
+
+	for _, src := range table {
+		t.Run(src, func(t *testing.T) {
+			tree, err := parser.ParseString(src, "test.goore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got ast.Program
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatal(err)
+			}
+			if dump(tree) != dump(&got) {
+				t.Errorf("want <%v> got <%v>", dump(tree), dump(&got))
+			}
+			again, err := json.Marshal(&got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Errorf("want <%s> got <%s>", data, again)
+			}
+			if (tree.Err == nil) != (got.Err == nil) {
+				t.Errorf("want error <%v> got <%v>", tree.Err, got.Err)
+			}
+		})
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	tree := parse(t, "x - 1")
+	data, err := json.Marshal(tree.Statements[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	loc := func(sc, ec, so, eo int) string {
+		return fmt.Sprintf(`"loc":{"startLine":1,"startColumn":%d,"endLine":1,"endColumn":%d,"startOffset":%d,"endOffset":%d,"startColumnUTF16":%d,"endColumnUTF16":%d}`, sc, ec, so, eo, so+1, eo+1)
+	}
+	want := `{"type":"ExpressionStatement",` + loc(1, 6, 0, 5) + `,` +
+		`"expression":{"type":"InfixExpression",` + loc(1, 5, 0, 5) + `,"operator":"Sub",` +
+		`"left":{"type":"Identifier",` + loc(1, 1, 0, 1) + `,"name":"x"},` +
+		`"right":{"type":"IntLiteral",` + loc(5, 5, 4, 5) + `,"value":1,"raw":"1"}}}`
+	if string(data) != want {
+		t.Errorf("want <%v> got <%v>", want, string(data))
+	}
+}
+
+func TestUnmarshalNode(t *testing.T) {
+	n, err := ast.UnmarshalNode([]byte(`{"type":"Identifier","loc":null,"name":"x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, ok := n.(*ast.Identifier); !ok || id.Name != "x" {
+		t.Errorf("want <%v> got <%#v>", "x", n)
+	}
+
+	table := []struct {
+		src string
+		err string
+	}{
+		{`{"type":"Frobnicate"}`, `unknown node type "Frobnicate"`},
+		{`{"type":"Return","expression":{"type":"Break"}}`, "is not an expression"},
+		{`{"type":"PrefixExpression","operator":"Frob"}`, `unknown operation "Frob"`},
+	}
+	for _, d := range table {
+		_, err := ast.UnmarshalNode([]byte(d.src))
+		if err == nil || !strings.Contains(err.Error(), d.err) {
+			t.Errorf("want <%v> got <%v>", d.err, err)
+		}
+	}
+}
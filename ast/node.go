@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/arikui1911/goore/diag"
 	"github.com/arikui1911/goore/token"
 )
 
@@ -22,10 +23,16 @@ type Statement interface {
 }
 
 type Program struct {
-	Loc        *token.Location
-	FileName   string
+	Loc      *token.Location
+	FileName string
+	// File is the file of a FileSet the program was parsed from, if any.
+	File       *token.File
 	Statements []Statement
-	Err        error
+	Comments   CommentMap
+	// Diagnostics lists every syntax error; Err is the same list as an
+	// error, nil when there is none.
+	Diagnostics diag.List
+	Err         error
 }
 
 func (*Program) statement() {}
@@ -58,6 +65,66 @@ func (n *Program) dump(w io.Writer, lv int) {
 	}
 }
 
+type Comment struct {
+	Loc  *token.Location
+	Text string
+}
+
+func (n *Comment) Location() *token.Location {
+	return n.Loc
+}
+
+func (n *Comment) dump(w io.Writer, lv int) {
+	dumpHeader(n, w, lv)
+	fmt.Fprintf(w, ": %#v\n", n.Text)
+}
+
+// Comments are the comments attached to one node. Leading ones precede
+// it, Trailing ones follow it (the first possibly on its last line), and
+// Inner ones sit inside its otherwise empty block, or right behind the
+// operator, colon or bracket an expression has before its last operand.
+type Comments struct {
+	Leading  []*Comment
+	Trailing []*Comment
+	Inner    []*Comment
+}
+
+type CommentMap map[Node]*Comments
+
+func (m CommentMap) Get(n Node) *Comments {
+	if c, ok := m[n]; ok {
+		return c
+	}
+	return &Comments{}
+}
+
+func (m CommentMap) add(n Node) *Comments {
+	c, ok := m[n]
+	if !ok {
+		c = &Comments{}
+		m[n] = c
+	}
+	return c
+}
+
+func (m CommentMap) AddLeading(n Node, cs ...*Comment) {
+	if len(cs) > 0 {
+		m.add(n).Leading = append(m.add(n).Leading, cs...)
+	}
+}
+
+func (m CommentMap) AddTrailing(n Node, cs ...*Comment) {
+	if len(cs) > 0 {
+		m.add(n).Trailing = append(m.add(n).Trailing, cs...)
+	}
+}
+
+func (m CommentMap) AddInner(n Node, cs ...*Comment) {
+	if len(cs) > 0 {
+		m.add(n).Inner = append(m.add(n).Inner, cs...)
+	}
+}
+
 type InvalidStatement struct {
 	Loc *token.Location
 	Err error
@@ -121,6 +188,37 @@ func (n *While) dump(w io.Writer, lv int) {
 	}
 }
 
+// For runs Body for each entry of Iterable, with one variable for the
+// element, or the key of a hash, or with two for the index or key and the
+// value.
+type For struct {
+	Loc      *token.Location
+	Vars     []*Identifier
+	Iterable Expression
+	Body     []Statement
+}
+
+func (*For) statement() {}
+
+func (n *For) Location() *token.Location {
+	return n.Loc
+}
+
+func (n *For) dump(w io.Writer, lv int) {
+	dumpHeader(n, w, lv)
+	fmt.Fprintln(w, ":")
+	attrHeader("Vars", w, lv+1)
+	for _, v := range n.Vars {
+		v.dump(w, lv+1)
+	}
+	attrHeader("Iterable", w, lv+1)
+	n.Iterable.dump(w, lv+1)
+	attrHeader("Body", w, lv+1)
+	for _, s := range n.Body {
+		s.dump(w, lv+1)
+	}
+}
+
 type Break struct {
 	Loc *token.Location
 }
@@ -194,6 +292,9 @@ func (n *If) dump(w io.Writer, lv int) {
 	for _, s := range n.Body {
 		s.dump(w, lv+1)
 	}
+	if n.Alt == nil {
+		return
+	}
 	attrHeader("Alt", w, lv+1)
 	n.Alt.dump(w, lv+1)
 }
@@ -239,6 +340,24 @@ type Expression interface {
 	expression()
 }
 
+// InvalidExpression stands in for a piece of an expression that failed to
+// parse, so that the rest of the expression is kept.
+type InvalidExpression struct {
+	Loc *token.Location
+	Err error
+}
+
+func (*InvalidExpression) expression() {}
+
+func (n *InvalidExpression) Location() *token.Location {
+	return n.Loc
+}
+
+func (n *InvalidExpression) dump(w io.Writer, lv int) {
+	dumpHeader(n, w, lv)
+	fmt.Fprintf(w, ": %v\n", n.Err)
+}
+
 type Identifier struct {
 	Loc  *token.Location
 	Name string
@@ -289,6 +408,8 @@ func (n *BoolLiteral) dump(w io.Writer, lv int) {
 type IntLiteral struct {
 	Loc   *token.Location
 	Value int
+	// Raw is the literal as written in the source, if any.
+	Raw string
 }
 
 func (*IntLiteral) expression() {}
@@ -305,6 +426,8 @@ func (n *IntLiteral) dump(w io.Writer, lv int) {
 type FloatLiteral struct {
 	Loc   *token.Location
 	Value float64
+	// Raw is the literal as written in the source, if any.
+	Raw string
 }
 
 func (*FloatLiteral) expression() {}
@@ -334,6 +457,28 @@ func (n *StringLiteral) dump(w io.Writer, lv int) {
 	fmt.Fprintf(w, ": %#v\n", n.Value)
 }
 
+// InterpolatedString is a string literal with embedded expressions. Parts
+// are StringLiterals for the text and the expressions between, whose
+// values are put into the string as they print.
+type InterpolatedString struct {
+	Loc   *token.Location
+	Parts []Expression
+}
+
+func (*InterpolatedString) expression() {}
+
+func (n *InterpolatedString) Location() *token.Location {
+	return n.Loc
+}
+
+func (n *InterpolatedString) dump(w io.Writer, lv int) {
+	dumpHeader(n, w, lv)
+	fmt.Fprintln(w, ":")
+	for _, x := range n.Parts {
+		x.dump(w, lv+1)
+	}
+}
+
 //go:generate stringer -type=Operation node.go
 type Operation int
 
@@ -353,6 +498,9 @@ const (
 	Mul
 	Div
 	Mod
+
+	And
+	Or
 )
 
 type PrefixExpression struct {
@@ -479,6 +627,30 @@ func (n *InfixExpression) dump(w io.Writer, lv int) {
 	n.Right.dump(w, lv+1)
 }
 
+// LogicalExpression is an And or Or, which evaluates Right only when Left
+// does not decide the value, and is then Left itself.
+type LogicalExpression struct {
+	Loc      *token.Location
+	Operator Operation
+	Left     Expression
+	Right    Expression
+}
+
+func (*LogicalExpression) expression() {}
+
+func (n *LogicalExpression) Location() *token.Location {
+	return n.Loc
+}
+
+func (n *LogicalExpression) dump(w io.Writer, lv int) {
+	dumpHeader(n, w, lv)
+	fmt.Fprintf(w, ": %v\n", n.Operator)
+	attrHeader("Left", w, lv+1)
+	n.Left.dump(w, lv+1)
+	attrHeader("Right", w, lv+1)
+	n.Right.dump(w, lv+1)
+}
+
 type Call struct {
 	Loc       *token.Location
 	Function  Expression
@@ -564,3 +736,21 @@ func (n *KeyAssign) dump(w io.Writer, lv int) {
 	attrHeader("Right", w, lv+1)
 	n.Right.dump(w, lv+1)
 }
+
+// SourceText returns the text of src that n was parsed from.
+func SourceText(src string, n Node) string {
+	loc := n.Location()
+	if loc == nil || loc.StartOffset < 0 || loc.EndOffset > len(src) || loc.StartOffset > loc.EndOffset {
+		return ""
+	}
+	return src[loc.StartOffset:loc.EndOffset]
+}
+
+// Pos returns the position in the file set of the program where n, a
+// node of it, begins, or token.NoPos when it was parsed without a set.
+func (n *Program) Pos(node Node) token.Pos {
+	if n.File == nil || node.Location() == nil {
+		return token.NoPos
+	}
+	return n.File.Pos(node.Location().StartOffset)
+}
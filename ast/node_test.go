@@ -0,0 +1,39 @@
+package ast_test
+
+import (
+	"testing"
+
+	"github.com/arikui1911/goore/ast"
+)
+
+func TestSourceText(t *testing.T) {
+	src := "def f = -> (ä) {\n  ä * 2\n}\nf(\"𝄞\", [1, 2])[0]"
+	tree := parse(t, src)
+	got := []string{}
+	ast.Inspect(tree, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FunctionLiteral, *ast.InfixExpression, *ast.Call, *ast.KeyAccess, *ast.StringLiteral, *ast.ArrayLiteral:
+			got = append(got, ast.SourceText(src, n))
+		}
+		return true
+	})
+	want := []string{
+		"-> (ä) {\n  ä * 2\n}",
+		"ä * 2",
+		"f(\"𝄞\", [1, 2])[0]",
+		"f(\"𝄞\", [1, 2])",
+		"\"𝄞\"",
+		"[1, 2]",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want <%q> got <%q>", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("want <%q> got <%q>", want[i], got[i])
+		}
+	}
+	if got := ast.SourceText(src, tree); got != src {
+		t.Errorf("want <%q> got <%q>", src, got)
+	}
+}
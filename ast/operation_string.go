@@ -22,11 +22,13 @@ func _() {
 	_ = x[Mul-11]
 	_ = x[Div-12]
 	_ = x[Mod-13]
+	_ = x[And-14]
+	_ = x[Or-15]
 }
 
-const _Operation_name = "PlusMinusNotEqNeLeGeLtGtAddSubMulDivMod"
+const _Operation_name = "PlusMinusNotEqNeLeGeLtGtAddSubMulDivModAndOr"
 
-var _Operation_index = [...]uint8{0, 4, 9, 12, 14, 16, 18, 20, 22, 24, 27, 30, 33, 36, 39}
+var _Operation_index = [...]uint8{0, 4, 9, 12, 14, 16, 18, 20, 22, 24, 27, 30, 33, 36, 39, 42, 44}
 
 func (i Operation) String() string {
 	if i < 0 || i >= Operation(len(_Operation_index)-1) {
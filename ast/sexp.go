@@ -0,0 +1,171 @@
+package ast
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DumpSexp writes tree as a single line S-expression without locations,
+// e.g. `(infix Add (int 1) (ident x))` for `1 + x`. Statement lists are
+// grouped as `(block ...)`.
+func DumpSexp(tree Node, dest io.Writer) {
+	var b strings.Builder
+	sexp(&b, tree)
+	b.WriteString("\n")
+	io.WriteString(dest, b.String())
+}
+
+// Sexp returns the S-expression DumpSexp writes for n, without the newline.
+func Sexp(n Node) string {
+	var b strings.Builder
+	sexp(&b, n)
+	return b.String()
+}
+
+func sexp(b *strings.Builder, node Node) {
+	open := func(tag string, attrs ...string) {
+		b.WriteString("(" + tag)
+		for _, a := range attrs {
+			b.WriteString(" " + a)
+		}
+	}
+	child := func(n Node) {
+		b.WriteString(" ")
+		sexp(b, n)
+	}
+	block := func(stmts []Statement) {
+		b.WriteString(" (block")
+		for _, s := range stmts {
+			child(s)
+		}
+		b.WriteString(")")
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		open("program")
+		for _, s := range n.Statements {
+			child(s)
+		}
+	case *Comment:
+		open("comment", strconv.Quote(n.Text))
+	case *InvalidStatement:
+		open("invalid", strconv.Quote(n.Err.Error()))
+	case *InvalidExpression:
+		open("invalid-expr", strconv.Quote(n.Err.Error()))
+	case *Def:
+		open("def")
+		child(n.Name)
+		if n.Init != nil {
+			child(n.Init)
+		}
+	case *While:
+		open("while")
+		child(n.Cond)
+		block(n.Body)
+	case *For:
+		open("for")
+		b.WriteString(" (vars")
+		for _, v := range n.Vars {
+			child(v)
+		}
+		b.WriteString(")")
+		child(n.Iterable)
+		block(n.Body)
+	case *Break:
+		open("break")
+	case *Continue:
+		open("continue")
+	case *Return:
+		open("return")
+		if n.Expression != nil {
+			child(n.Expression)
+		}
+	case *If:
+		open("if")
+		child(n.Test)
+		block(n.Body)
+		if n.Alt != nil {
+			child(n.Alt)
+		}
+	case *Else:
+		open("else")
+		block(n.Body)
+	case *ExpressionStatement:
+		open("expr")
+		child(n.Expression)
+	case *Identifier:
+		open("ident", n.Name)
+	case *NilLiteral:
+		open("nil")
+	case *BoolLiteral:
+		open("bool", strconv.FormatBool(n.Value))
+	case *IntLiteral:
+		open("int", strconv.Itoa(n.Value))
+	case *FloatLiteral:
+		open("float", strconv.FormatFloat(n.Value, 'g', -1, 64))
+	case *StringLiteral:
+		open("string", strconv.Quote(n.Value))
+	case *InterpolatedString:
+		open("interp")
+		for _, x := range n.Parts {
+			child(x)
+		}
+	case *PrefixExpression:
+		open("prefix", n.Operator.String())
+		child(n.Right)
+	case *ArrayLiteral:
+		open("array")
+		for _, e := range n.Elements {
+			child(e)
+		}
+	case *HashLiteral:
+		open("hash")
+		for _, e := range n.Pairs {
+			child(e)
+		}
+	case *HashEntry:
+		open("entry")
+		child(n.Key)
+		child(n.Value)
+	case *FunctionLiteral:
+		open("fn")
+		b.WriteString(" (params")
+		for _, p := range n.Parameters {
+			child(p)
+		}
+		b.WriteString(")")
+		block(n.Statements)
+	case *InfixExpression:
+		open("infix", n.Operator.String())
+		child(n.Left)
+		child(n.Right)
+	case *LogicalExpression:
+		open("logical", n.Operator.String())
+		child(n.Left)
+		child(n.Right)
+	case *Call:
+		open("call")
+		child(n.Function)
+		for _, a := range n.Arguments {
+			child(a)
+		}
+	case *KeyAccess:
+		open("key-access")
+		child(n.Container)
+		child(n.Key)
+	case *Let:
+		open("let")
+		child(n.Left)
+		child(n.Right)
+	case *KeyAssign:
+		open("key-assign")
+		child(n.Left)
+		child(n.Right)
+	default:
+		panic(fmt.Sprintf("ast.DumpSexp: unexpected node type %T", n))
+	}
+	b.WriteString(")")
+}
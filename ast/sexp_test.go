@@ -0,0 +1,44 @@
+package ast_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/arikui1911/goore/ast"
+)
+
+func TestSexp(t *testing.T) {
+	table := []struct {
+		src  string
+		want string
+	}{
+		{"1 + x", "(program (expr (infix Add (int 1) (ident x))))"},
+		{"a || b && !c", "(program (expr (logical Or (ident a) (logical And (ident b) (prefix Not (ident c))))))"},
+		{"def x\ndef y = nil", "(program (def (ident x)) (def (ident y) (nil)))"},
+		{"x += 2.5", "(program (expr (let (ident x) (infix Add (ident x) (float 2.5)))))"},
+		{"while !a { break\ncontinue }", "(program (while (prefix Not (ident a)) (block (break) (continue))))"},
+		{"for k, v in h { k }", "(program (for (vars (ident k) (ident v)) (ident h) (block (expr (ident k)))))"},
+		{`"a#{b}c"`, `(program (expr (interp (string "a") (ident b) (string "c"))))`},
+		{"if a { return } else { 1 }", "(program (expr (if (ident a) (block (return)) (else (block (expr (int 1)))))))"},
+		{"-> (a, b) { return a }", "(program (expr (fn (params (ident a) (ident b)) (block (return (ident a))))))"},
+		{"h[\"k\"] = [true, {1: f()}]", "(program (expr (key-assign (key-access (ident h) (string \"k\")) (array (bool true) (hash (entry (int 1) (call (ident f))))))))"},
+	}
+
+	for _, d := range table {
+		t.Run(d.src, func(t *testing.T) {
+			got := ast.Sexp(parse(t, d.src))
+			if got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+		})
+	}
+}
+
+func TestDumpSexp(t *testing.T) {
+	var buf bytes.Buffer
+	ast.DumpSexp(parse(t, "x"), &buf)
+	want := "(program (expr (ident x)))\n"
+	if buf.String() != want {
+		t.Errorf("want <%v> got <%v>", want, buf.String())
+	}
+}
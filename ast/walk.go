@@ -0,0 +1,124 @@
+package ast
+
+import "fmt"
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children of
+// node with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses the tree rooted at node in depth-first order. Children are
+// visited in source order. The target of a compound assignment such as
+// `x += 1` is shared with the left operand of its desugared right side, so
+// it is visited twice.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		walkStatements(v, n.Statements)
+	case *InvalidStatement, *InvalidExpression, *Break, *Continue, *Comment,
+		*Identifier, *NilLiteral, *BoolLiteral, *IntLiteral, *FloatLiteral, *StringLiteral:
+		// leaves
+	case *Def:
+		Walk(v, n.Name)
+		if n.Init != nil {
+			Walk(v, n.Init)
+		}
+	case *While:
+		Walk(v, n.Cond)
+		walkStatements(v, n.Body)
+	case *For:
+		for _, x := range n.Vars {
+			Walk(v, x)
+		}
+		Walk(v, n.Iterable)
+		walkStatements(v, n.Body)
+	case *Return:
+		if n.Expression != nil {
+			Walk(v, n.Expression)
+		}
+	case *If:
+		Walk(v, n.Test)
+		walkStatements(v, n.Body)
+		if n.Alt != nil {
+			Walk(v, n.Alt)
+		}
+	case *Else:
+		walkStatements(v, n.Body)
+	case *ExpressionStatement:
+		Walk(v, n.Expression)
+	case *PrefixExpression:
+		Walk(v, n.Right)
+	case *InterpolatedString:
+		walkExpressions(v, n.Parts)
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+	case *HashLiteral:
+		for _, e := range n.Pairs {
+			Walk(v, e)
+		}
+	case *HashEntry:
+		Walk(v, n.Key)
+		Walk(v, n.Value)
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		walkStatements(v, n.Statements)
+	case *InfixExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *LogicalExpression:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *Call:
+		Walk(v, n.Function)
+		walkExpressions(v, n.Arguments)
+	case *KeyAccess:
+		Walk(v, n.Container)
+		Walk(v, n.Key)
+	case *Let:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	case *KeyAssign:
+		Walk(v, n.Left)
+		Walk(v, n.Right)
+	default:
+		panic(fmt.Sprintf("ast.Walk: unexpected node type %T", n))
+	}
+
+	v.Visit(nil)
+}
+
+func walkStatements(v Visitor, stmts []Statement) {
+	for _, s := range stmts {
+		Walk(v, s)
+	}
+}
+
+func walkExpressions(v Visitor, xs []Expression) {
+	for _, x := range xs {
+		Walk(v, x)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the tree rooted at node in depth-first order, calling
+// f(node) first and then for each child unless f returns false. After the
+// children, f(nil) is called.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
@@ -0,0 +1,201 @@
+package ast_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/format"
+	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/token"
+)
+
+func parse(t *testing.T, src string) *ast.Program {
+	t.Helper()
+	tree, err := parser.ParseString(src, "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Err != nil {
+		t.Fatal(tree.Err)
+	}
+	return tree
+}
+
+// formatted prints prog without blank lines, which the formatter keeps
+// from gaps between locations that edited trees no longer keep consistent.
+func formatted(t *testing.T, prog *ast.Program) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := format.Fprint(&buf, prog); err != nil {
+		t.Fatal(err)
+	}
+	return strings.ReplaceAll(buf.String(), "\n\n", "\n")
+}
+
+func TestInspect(t *testing.T) {
+	tree := parse(t, "def f = -> (a) { a + 1 }\nwhile f(2) { if x { y } else { [z, {\"k\": w}] } }")
+	got := []string{}
+	ast.Inspect(tree, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok {
+			got = append(got, id.Name)
+		}
+		return true
+	})
+	want := "f a a f x y z w"
+	if strings.Join(got, " ") != want {
+		t.Errorf("want <%v> got <%v>", want, strings.Join(got, " "))
+	}
+}
+
+func TestInspectPrune(t *testing.T) {
+	tree := parse(t, "def f = -> (a) { a }\nb")
+	got := []string{}
+	ast.Inspect(tree, func(n ast.Node) bool {
+		if id, ok := n.(*ast.Identifier); ok {
+			got = append(got, id.Name)
+		}
+		_, ok := n.(*ast.FunctionLiteral)
+		return !ok
+	})
+	want := "f b"
+	if strings.Join(got, " ") != want {
+		t.Errorf("want <%v> got <%v>", want, strings.Join(got, " "))
+	}
+}
+
+func TestInspectBalanced(t *testing.T) {
+	tree := parse(t, "x = [1, 2]\nwhile x { break }")
+	depth := 0
+	ast.Inspect(tree, func(n ast.Node) bool {
+		if n == nil {
+			depth--
+		} else {
+			depth++
+		}
+		return true
+	})
+	if depth != 0 {
+		t.Errorf("want <%d> got <%d>", 0, depth)
+	}
+}
+
+func integer(v int) *ast.IntLiteral {
+	return &ast.IntLiteral{Loc: &token.Location{}, Value: v}
+}
+
+func statement(x ast.Expression) *ast.ExpressionStatement {
+	return &ast.ExpressionStatement{Loc: &token.Location{}, Expression: x}
+}
+
+func TestApply(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		pre  ast.ApplyFunc
+		want string
+	}{
+		{
+			"replace expression",
+			"puts(1 + 2)",
+			func(c *ast.Cursor) bool {
+				if x, ok := c.Node().(*ast.IntLiteral); ok {
+					c.Replace(integer(x.Value * 10))
+				}
+				return true
+			},
+			"puts(10 + 20)\n",
+		},
+		{
+			"delete statements",
+			"a\nwhile y {\n\tbreak\n}\nb\nwhile x {\n\tbreak\n\tc\n}",
+			func(c *ast.Cursor) bool {
+				if _, ok := c.Node().(*ast.Break); ok {
+					c.Delete()
+				}
+				return true
+			},
+			"a\nwhile y {}\nb\nwhile x {\n\tc\n}\n",
+		},
+		{
+			"insert statements",
+			"if a {\n\tb\n} else {\n\tc\n}\ndef f = -> {\n\td\n}",
+			func(c *ast.Cursor) bool {
+				_, top := c.Parent().(*ast.Program)
+				if _, ok := c.Node().(*ast.ExpressionStatement); ok && !top {
+					c.InsertBefore(statement(integer(1)))
+					c.InsertAfter(statement(integer(2)))
+				}
+				return true
+			},
+			"if a {\n\t1\n\tb\n\t2\n} else {\n\t1\n\tc\n\t2\n}\ndef f = -> {\n\t1\n\td\n\t2\n}\n",
+		},
+		{
+			"replace root",
+			"a",
+			func(c *ast.Cursor) bool {
+				if c.Name() == "Root" {
+					c.Replace(&ast.Program{Loc: &token.Location{}, Statements: []ast.Statement{statement(integer(1))}})
+				}
+				return true
+			},
+			"1\n",
+		},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree := ast.Apply(parse(t, d.src), d.pre, nil).(*ast.Program)
+			if got := formatted(t, tree); got != d.want {
+				t.Errorf("want <%#v> got <%#v>", d.want, got)
+			}
+		})
+	}
+}
+
+func TestApplyCursor(t *testing.T) {
+	tree := parse(t, "f(a, b)\nwhile x { y }")
+	got := []string{}
+	ast.Apply(tree, func(c *ast.Cursor) bool {
+		if id, ok := c.Node().(*ast.Identifier); ok {
+			got = append(got, fmt.Sprintf("%s:%T.%s[%d]", id.Name, c.Parent(), c.Name(), c.Index()))
+		}
+		return true
+	}, nil)
+	want := "f:*ast.Call.Function[-1] a:*ast.Call.Arguments[0] b:*ast.Call.Arguments[1] x:*ast.While.Cond[-1] y:*ast.ExpressionStatement.Expression[-1]"
+	if strings.Join(got, " ") != want {
+		t.Errorf("want <%v> got <%v>", want, strings.Join(got, " "))
+	}
+}
+
+func TestApplyAbort(t *testing.T) {
+	tree := parse(t, "a\nb\nc")
+	got := []string{}
+	ast.Apply(tree, nil, func(c *ast.Cursor) bool {
+		id, ok := c.Node().(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		got = append(got, id.Name)
+		return id.Name != "b"
+	})
+	if want := "a b"; strings.Join(got, " ") != want {
+		t.Errorf("want <%v> got <%v>", want, strings.Join(got, " "))
+	}
+}
+
+func TestApplyDeleteOutsideList(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("want panic got none")
+		}
+	}()
+	ast.Apply(parse(t, "a"), func(c *ast.Cursor) bool {
+		if _, ok := c.Node().(*ast.Identifier); ok {
+			c.Delete()
+		}
+		return true
+	}, nil)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/arikui1911/goore/format"
+)
+
+func runFmt(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	write := fs.Bool("w", false, "write result to the source file instead of standard output")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: goore fmt [-w] FILE")
+		return exitUsage
+	}
+	if *write && fs.Arg(0) == "-" {
+		fmt.Fprintln(stderr, "goore fmt: cannot use -w with standard input")
+		return exitUsage
+	}
+
+	src, fileName, err := readSource(fs.Arg(0), stdin)
+	if err != nil {
+		printError(stderr, err)
+		return exitError
+	}
+
+	out, err := format.Source(src, fileName)
+	if err != nil {
+		printDiagnostics(stderr, src, err)
+		return exitError
+	}
+	if *write {
+		if err := os.WriteFile(fileName, out, 0o644); err != nil {
+			printError(stderr, err)
+			return exitError
+		}
+		return exitOK
+	}
+	stdout.Write(out)
+	return exitOK
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/lsp"
+)
+
+func runLSP(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) != 0 {
+		fmt.Fprintln(stderr, "usage: goore lsp")
+		return exitUsage
+	}
+	if err := lsp.Serve(stdin, stdout); err != nil {
+		printError(stderr, err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/arikui1911/goore/diag"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	run     func(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"run", "run [-vm] FILE", "execute a goore program", runRun},
+		{"parse", "parse [-json | -sexp | -dot] FILE...", "print the syntax tree of a goore program", runParse},
+		{"tokens", "tokens FILE", "print the tokens of a goore program", runTokens},
+		{"fmt", "fmt [-w] FILE", "print a goore program in canonical format", runFmt},
+		{"repl", "repl", "start an interactive session", runREPL},
+		{"lsp", "lsp", "start a language server on standard input and output", runLSP},
+	}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitUsage
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdin, stdout, stderr)
+		}
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stdout)
+		return exitOK
+	}
+	fmt.Fprintf(stderr, "goore: unknown command %q\n", args[0])
+	usage(stderr)
+	return exitUsage
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: goore COMMAND [ARGS]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-20s %s\n", c.usage, c.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "FILE may be - to read from standard input.")
+}
+
+// openSource opens the file named by a command argument, where "-" is
+// standard input.
+func openSource(name string, stdin io.Reader) (io.ReadCloser, string, error) {
+	if name == "-" {
+		return io.NopCloser(stdin), "<stdin>", nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return f, name, nil
+}
+
+// readSource reads the whole file named by a command argument, where "-"
+// is standard input.
+func readSource(name string, stdin io.Reader) ([]byte, string, error) {
+	src, fileName, err := openSource(name, stdin)
+	if err != nil {
+		return nil, "", err
+	}
+	defer src.Close()
+	b, err := io.ReadAll(src)
+	if err != nil {
+		return nil, "", err
+	}
+	return b, fileName, nil
+}
+
+func printError(w io.Writer, err error) {
+	fmt.Fprintln(w, strings.TrimRight(err.Error(), "\n"))
+}
+
+// printDiagnostics renders the diagnostics in err with the lines of src
+// they point at, in color when w is a terminal and NO_COLOR is unset.
+func printDiagnostics(w io.Writer, src []byte, err error) {
+	p := &diag.Printer{Color: isTerminal(w) && os.Getenv("NO_COLOR") == ""}
+	p.Fprint(w, src, err)
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
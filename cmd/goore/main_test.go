@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	hello := filepath.Join(dir, "hello.goore")
+	if err := os.WriteFile(hello, []byte("def greet = -> (s) { puts(\"Hello, \" + s) }\ngreet(\"goore\")\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	table := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{"no command", nil, "", exitUsage, "", "usage:"},
+		{"unknown command", []string{"frobnicate"}, "", exitUsage, "", "unknown command"},
+		{"run", []string{"run", hello}, "", exitOK, "Hello, goore\n", ""},
+		{"run vm", []string{"run", "-vm", hello}, "", exitOK, "Hello, goore\n", ""},
+		{"run stdin", []string{"run", "-"}, "puts(1 + 2)", exitOK, "3\n", ""},
+		{"run missing file", []string{"run", filepath.Join(dir, "missing.goore")}, "", exitError, "", "no such file"},
+		{"run runtime error", []string{"run", "-"}, "1 / 0", exitError, "", "<stdin>:(1:1):(1:5): division by zero"},
+		{"run syntax error", []string{"run", "-"}, "def 1", exitError, "", "unexpected token"},
+		{"run syntax error snippet", []string{"run", "-"}, "def 1", exitError, "", "1 | def 1\n  |     ^\n"},
+		{"parse", []string{"parse", "-"}, "x", exitOK, "*ast.Identifier: x", ""},
+		{"parse if", []string{"parse", "-"}, "if x { 1 }", exitOK, "*ast.If", ""},
+		{"parse json", []string{"parse", "-json", "-"}, "1 + x", exitOK, "\"operator\": \"Add\"", ""},
+		{"parse sexp", []string{"parse", "-sexp", "-"}, "1 + x", exitOK, "(program (expr (infix Add (int 1) (ident x))))\n", ""},
+		{"parse dot", []string{"parse", "-dot", "-"}, "1 + x", exitOK, "n2 -> n3 [label=\"Left\"];", ""},
+		{"parse two formats", []string{"parse", "-json", "-dot", "-"}, "x", exitUsage, "", "usage:"},
+		{"parse files", []string{"parse", "-sexp", hello, hello}, "", exitOK, "(program (def (ident greet)", ""},
+		{"parse syntax error", []string{"parse", "-"}, "def 1", exitError, "*ast.InvalidStatement", "unexpected token"},
+		{"tokens", []string{"tokens", "-"}, "x + 1", exitOK, "Identifier\t\"x\"", ""},
+		{"tokens error", []string{"tokens", "-"}, `"abc`, exitError, "", "<stdin>:(1:1):(1:5): unterminated string literal"},
+		{"fmt", []string{"fmt", "-"}, "x=[1,2]", exitOK, "x = [1, 2]\n", ""},
+		{"fmt syntax error", []string{"fmt", "-"}, "def 1", exitError, "", "unexpected token"},
+		{"fmt write stdin", []string{"fmt", "-w", "-"}, "x", exitUsage, "", "cannot use -w"},
+		{"tokens usage", []string{"tokens"}, "", exitUsage, "", "usage:"},
+		{"lsp closed input", []string{"lsp"}, "", exitOK, "", ""},
+		{"lsp usage", []string{"lsp", "x"}, "", exitUsage, "", "usage:"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(d.args, strings.NewReader(d.stdin), &stdout, &stderr)
+			if code != d.code {
+				t.Errorf("want exit code <%d> got <%d> (stderr: %s)", d.code, code, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), d.stdout) {
+				t.Errorf("want stdout containing <%#v> got <%#v>", d.stdout, stdout.String())
+			}
+			if !strings.Contains(stderr.String(), d.stderr) {
+				t.Errorf("want stderr containing <%#v> got <%#v>", d.stderr, stderr.String())
+			}
+		})
+	}
+}
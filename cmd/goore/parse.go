@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/token"
+)
+
+func runParse(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print the syntax tree as JSON")
+	asSexp := fs.Bool("sexp", false, "print the syntax tree as an S-expression")
+	asDot := fs.Bool("dot", false, "print the syntax tree as a Graphviz graph")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() < 1 || btoi(*asJSON)+btoi(*asSexp)+btoi(*asDot) > 1 {
+		fmt.Fprintln(stderr, "usage: goore parse [-json | -sexp | -dot] FILE...")
+		return exitUsage
+	}
+
+	// all files share one set, so their positions never collide
+	fset := token.NewFileSet()
+	code := exitOK
+	for _, name := range fs.Args() {
+		src, fileName, err := readSource(name, stdin)
+		if err != nil {
+			printError(stderr, err)
+			return exitError
+		}
+
+		tree, err := parser.ParseFile(fset, fileName, string(src))
+		if err != nil {
+			printDiagnostics(stderr, src, err)
+			return exitError
+		}
+		switch {
+		case *asJSON:
+			enc := json.NewEncoder(stdout)
+			enc.SetEscapeHTML(false)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(tree); err != nil {
+				printError(stderr, err)
+				return exitError
+			}
+		case *asSexp:
+			ast.DumpSexp(tree, stdout)
+		case *asDot:
+			ast.DumpDot(tree, stdout)
+		default:
+			ast.Dump(tree, stdout)
+		}
+		if tree.Err != nil {
+			printDiagnostics(stderr, src, tree.Err)
+			code = exitError
+		}
+	}
+	return code
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/repl"
+)
+
+func runREPL(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) != 0 {
+		fmt.Fprintln(stderr, "usage: goore repl")
+		return exitUsage
+	}
+	if err := repl.Start(stdin, stdout); err != nil {
+		printError(stderr, err)
+		return exitError
+	}
+	return exitOK
+}
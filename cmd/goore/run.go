@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/compiler"
+	"github.com/arikui1911/goore/eval"
+	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/vm"
+)
+
+func runRun(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	useVM := fs.Bool("vm", false, "execute with the bytecode VM instead of the tree-walking evaluator")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "usage: goore run [-vm] FILE")
+		return exitUsage
+	}
+
+	src, fileName, err := readSource(fs.Arg(0), stdin)
+	if err != nil {
+		printError(stderr, err)
+		return exitError
+	}
+
+	tree, err := parser.ParseString(string(src), fileName)
+	if err != nil {
+		printDiagnostics(stderr, src, err)
+		return exitError
+	}
+	if tree.Err != nil {
+		printDiagnostics(stderr, src, tree.Err)
+		return exitError
+	}
+
+	if *useVM {
+		bc, err := compiler.Compile(tree)
+		if err != nil {
+			printError(stderr, err)
+			return exitError
+		}
+		if _, err := vm.New(bc, stdout).Run(); err != nil {
+			printError(stderr, err)
+			return exitError
+		}
+		return exitOK
+	}
+	if _, err := eval.New(stdout).Run(tree); err != nil {
+		printError(stderr, err)
+		return exitError
+	}
+	return exitOK
+}
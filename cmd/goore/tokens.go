@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/lexer"
+	"github.com/arikui1911/goore/token"
+)
+
+func runTokens(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	if len(args) != 1 {
+		fmt.Fprintln(stderr, "usage: goore tokens FILE")
+		return exitUsage
+	}
+
+	src, fileName, err := openSource(args[0], stdin)
+	if err != nil {
+		printError(stderr, err)
+		return exitError
+	}
+	defer src.Close()
+
+	l := lexer.New(src)
+	for {
+		t, err := l.NextToken()
+		if err != nil {
+			fmt.Fprintf(stderr, "%s:%v\n", fileName, err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "%s\t%s\t%q\n", t.Location, t.Tag, t.Value)
+		if t.Tag == token.EOF {
+			return exitOK
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/arikui1911/goore/object"
+	"github.com/arikui1911/goore/token"
+)
+
+type Bytecode struct {
+	FileName  string
+	Main      *CompiledFunction
+	Constants []object.Object
+	Globals   []string
+}
+
+type CompiledFunction struct {
+	Instructions Instructions
+	NumLocals    int
+	NumParams    int
+	Locations    map[int]*token.Location
+}
+
+func (*CompiledFunction) Type() object.Type { return object.FunctionType }
+
+func (f *CompiledFunction) String() string { return f.Inspect() }
+
+func (f *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("#<compiled-function:%p>", f)
+}
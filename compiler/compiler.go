@@ -0,0 +1,342 @@
+package compiler
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/object"
+	"github.com/arikui1911/goore/token"
+)
+
+func Compile(prog *ast.Program) (*Bytecode, error) {
+	return New().Compile(prog)
+}
+
+type Compiler struct {
+	fileName    string
+	constants   []object.Object
+	globals     map[string]int
+	globalNames []string
+	fn          *funcState
+}
+
+func New() *Compiler {
+	c := &Compiler{globals: map[string]int{}}
+	for _, b := range object.Builtins(io.Discard) {
+		c.globalIndex(nil, b.Name)
+	}
+	return c
+}
+
+func (c *Compiler) Compile(prog *ast.Program) (*Bytecode, error) {
+	if prog.Err != nil {
+		return nil, prog.Err
+	}
+	c.fileName = prog.FileName
+	c.fn = newFuncState(nil)
+	if err := compileStatements(c, prog.Statements); err != nil {
+		return nil, err
+	}
+	c.emit(nil, OpReturn)
+	main := c.fn.function(0)
+	c.fn = nil
+	return &Bytecode{
+		FileName:  c.fileName,
+		Main:      main,
+		Constants: c.constants,
+		Globals:   c.globalNames,
+	}, nil
+}
+
+type local struct {
+	slot     int
+	captured bool
+	// ahead tells that the local is declared ahead of its def, which
+	// only closures see before the def is compiled.
+	ahead bool
+}
+
+type blockScope struct {
+	locals map[string]*local
+	base   int
+}
+
+type upvalue struct {
+	index   int
+	isLocal bool
+}
+
+type loopState struct {
+	start int
+	depth int
+	// temps counts the values the loop itself keeps below depth, like the
+	// iterator of a for loop, which break drops as well.
+	temps  int
+	scopes int
+	breaks []int
+}
+
+// funcState holds what is being compiled for one function literal, or
+// for the top level program when enclosing is nil.
+type funcState struct {
+	enclosing *funcState
+	ins       Instructions
+	locations map[int]*token.Location
+	scopes    []*blockScope
+	nextSlot  int
+	maxSlot   int
+	upvalues  []upvalue
+	loops     []*loopState
+	// depth counts the temporaries pushed on top of the locals.
+	depth int
+}
+
+func newFuncState(enclosing *funcState) *funcState {
+	return &funcState{
+		enclosing: enclosing,
+		locations: map[int]*token.Location{},
+	}
+}
+
+func (fs *funcState) function(nParams int) *CompiledFunction {
+	return &CompiledFunction{
+		Instructions: fs.ins,
+		NumLocals:    fs.maxSlot,
+		NumParams:    nParams,
+		Locations:    fs.locations,
+	}
+}
+
+func (c *Compiler) errorf(loc *token.Location, format string, args ...any) error {
+	return fmt.Errorf("%s:%s: %s", c.fileName, loc, fmt.Sprintf(format, args...))
+}
+
+func (c *Compiler) emit(loc *token.Location, op Opcode, operands ...int) int {
+	fs := c.fn
+	pos := len(fs.ins)
+	fs.ins = append(fs.ins, Make(op, operands...)...)
+	if loc != nil {
+		fs.locations[pos] = loc
+	}
+	fs.depth += stackEffect(op, operands)
+	return pos
+}
+
+func stackEffect(op Opcode, operands []int) int {
+	switch op {
+	case OpConstant, OpNil, OpTrue, OpFalse, OpGetGlobal, OpGetLocal, OpGetUpvalue, OpClosure:
+		return 1
+	case OpPop, OpJumpIfFalse, OpDefineGlobal, OpInfix, OpIndex, OpReturn:
+		return -1
+	case OpJumpIfFalseOrPop, OpJumpIfTrueOrPop:
+		// the value stays only when jumping, to where the path falling
+		// through has pushed one again
+		return -1
+	case OpSetIndex:
+		return -2
+	case OpArray, OpConcat:
+		return 1 - operands[0]
+	case OpHash:
+		return 1 - 2*operands[0]
+	case OpCall:
+		return -operands[0]
+	case OpIterNext:
+		// the iterator is dropped only when jumping out of the loop
+		return operands[1]
+	}
+	return 0
+}
+
+func (c *Compiler) patchJump(pos int) error {
+	return c.patchJumpTo(pos, len(c.fn.ins))
+}
+
+// patchJumpTo sets the target of the jump at pos, which has to fit in its
+// 16-bit operand.
+func (c *Compiler) patchJumpTo(pos int, target int) error {
+	if target > math.MaxUint16 {
+		return c.errorf(c.fn.locations[pos], "jump target out of range")
+	}
+	copy(c.fn.ins[pos+1:], Make(OpJump, target)[1:])
+	return nil
+}
+
+// emitJump emits a jump back to target, which is known already.
+func (c *Compiler) emitJump(loc *token.Location, target int) error {
+	return c.patchJumpTo(c.emit(loc, OpJump, 0xffff), target)
+}
+
+func (c *Compiler) addConstant(loc *token.Location, o object.Object) (int, error) {
+	if len(c.constants) > math.MaxUint16 {
+		return 0, c.errorf(loc, "too many constants")
+	}
+	c.constants = append(c.constants, o)
+	return len(c.constants) - 1, nil
+}
+
+func (c *Compiler) emitConstant(loc *token.Location, o object.Object) error {
+	i, err := c.addConstant(loc, o)
+	if err != nil {
+		return err
+	}
+	c.emit(loc, OpConstant, i)
+	return nil
+}
+
+func (c *Compiler) globalIndex(loc *token.Location, name string) (int, error) {
+	if i, ok := c.globals[name]; ok {
+		return i, nil
+	}
+	if len(c.globalNames) > math.MaxUint16 {
+		return 0, c.errorf(loc, "too many global variables")
+	}
+	c.globalNames = append(c.globalNames, name)
+	c.globals[name] = len(c.globalNames) - 1
+	return c.globals[name], nil
+}
+
+func (c *Compiler) isTopLevel() bool {
+	return c.fn.enclosing == nil && len(c.fn.scopes) == 0
+}
+
+func (c *Compiler) pushScope() {
+	c.fn.scopes = append(c.fn.scopes, &blockScope{locals: map[string]*local{}, base: c.fn.nextSlot})
+}
+
+func (c *Compiler) popScope() {
+	fs := c.fn
+	s := fs.scopes[len(fs.scopes)-1]
+	fs.scopes = fs.scopes[:len(fs.scopes)-1]
+	for _, l := range s.locals {
+		if l.captured {
+			c.emit(nil, OpCloseUpvalues, s.base)
+			break
+		}
+	}
+	fs.nextSlot = s.base
+}
+
+func (c *Compiler) declareLocal(loc *token.Location, name string) (int, error) {
+	fs := c.fn
+	s := fs.scopes[len(fs.scopes)-1]
+	if l, ok := s.locals[name]; ok {
+		return l.slot, nil
+	}
+	if fs.nextSlot > math.MaxUint8 {
+		return 0, c.errorf(loc, "too many local variables")
+	}
+	s.locals[name] = &local{slot: fs.nextSlot}
+	fs.nextSlot++
+	fs.maxSlot = max(fs.maxSlot, fs.nextSlot)
+	return s.locals[name].slot, nil
+}
+
+// declareDefs declares the locals of the defs among stmts ahead of them,
+// so that a closure can call a function defined later in the block.
+func (c *Compiler) declareDefs(stmts []ast.Statement) error {
+	if c.isTopLevel() {
+		return nil
+	}
+	s := c.fn.scopes[len(c.fn.scopes)-1]
+	for _, st := range stmts {
+		d, ok := st.(*ast.Def)
+		if !ok {
+			continue
+		}
+		if _, ok := s.locals[d.Name.Name]; ok {
+			continue
+		}
+		if _, err := c.declareLocal(d.Loc, d.Name.Name); err != nil {
+			return err
+		}
+		s.locals[d.Name.Name].ahead = true
+	}
+	return nil
+}
+
+// resolveLocal finds the local name visible in fs. Locals declared ahead of
+// their def are skipped unless withAhead, as the evaluator defines them
+// only when the def runs.
+func resolveLocal(fs *funcState, name string, withAhead bool) *local {
+	for i := len(fs.scopes) - 1; i >= 0; i-- {
+		if l, ok := fs.scopes[i].locals[name]; ok && (withAhead || !l.ahead) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) resolveUpvalue(loc *token.Location, fs *funcState, name string) (int, bool, error) {
+	if fs.enclosing == nil {
+		return 0, false, nil
+	}
+	if l := resolveLocal(fs.enclosing, name, true); l != nil {
+		l.captured = true
+		i, err := c.addUpvalue(loc, fs, l.slot, true)
+		return i, true, err
+	}
+	i, ok, err := c.resolveUpvalue(loc, fs.enclosing, name)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	i, err = c.addUpvalue(loc, fs, i, false)
+	return i, true, err
+}
+
+// addUpvalue returns the index of the upvalue of fs, whose count has to fit
+// in the 8-bit operand of OpClosure.
+func (c *Compiler) addUpvalue(loc *token.Location, fs *funcState, index int, isLocal bool) (int, error) {
+	for i, u := range fs.upvalues {
+		if u.index == index && u.isLocal == isLocal {
+			return i, nil
+		}
+	}
+	if len(fs.upvalues) >= math.MaxUint8 {
+		return 0, c.errorf(loc, "too many upvalues")
+	}
+	fs.upvalues = append(fs.upvalues, upvalue{index: index, isLocal: isLocal})
+	return len(fs.upvalues) - 1, nil
+}
+
+// Names are resolved statically: a local visible at the point of use, a
+// local of an enclosing function captured as an upvalue, or else a global
+// looked up by name at run time.
+func (c *Compiler) emitGet(loc *token.Location, name string) error {
+	if l := resolveLocal(c.fn, name, false); l != nil {
+		c.emit(loc, OpGetLocal, l.slot)
+		return nil
+	}
+	if i, ok, err := c.resolveUpvalue(loc, c.fn, name); err != nil {
+		return err
+	} else if ok {
+		c.emit(loc, OpGetUpvalue, i)
+		return nil
+	}
+	i, err := c.globalIndex(loc, name)
+	if err != nil {
+		return err
+	}
+	c.emit(loc, OpGetGlobal, i)
+	return nil
+}
+
+func (c *Compiler) emitSet(loc *token.Location, name string) error {
+	if l := resolveLocal(c.fn, name, false); l != nil {
+		c.emit(loc, OpSetLocal, l.slot)
+		return nil
+	}
+	if i, ok, err := c.resolveUpvalue(loc, c.fn, name); err != nil {
+		return err
+	} else if ok {
+		c.emit(loc, OpSetUpvalue, i)
+		return nil
+	}
+	i, err := c.globalIndex(loc, name)
+	if err != nil {
+		return err
+	}
+	c.emit(loc, OpSetGlobal, i)
+	return nil
+}
@@ -0,0 +1,159 @@
+package compiler_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/compiler"
+	"github.com/arikui1911/goore/parser"
+)
+
+func TestCompileInstructions(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		want []string
+	}{
+		{"infix", `1 + 2`, []string{
+			"0000 OpConstant 0",
+			"0003 OpConstant 1",
+			"0006 OpInfix Add",
+			"0008 OpReturn",
+		}},
+		{"while", "def x = 1\nwhile x < 3 { x += 1 }", []string{
+			"0000 OpConstant 0",
+			"0003 OpDefineGlobal 7",
+			"0006 OpGetGlobal 7",
+			"0009 OpConstant 1",
+			"0012 OpInfix Lt",
+			"0014 OpJumpIfFalse 32",
+			"0017 OpGetGlobal 7",
+			"0020 OpConstant 2",
+			"0023 OpInfix Add",
+			"0025 OpSetGlobal 7",
+			"0028 OpPop",
+			"0029 OpJump 6",
+			"0032 OpNil",
+			"0033 OpReturn",
+		}},
+		{"and", `a && b`, []string{
+			"0000 OpGetGlobal 7",
+			"0003 OpJumpIfFalseOrPop 9",
+			"0006 OpGetGlobal 8",
+			"0009 OpReturn",
+		}},
+		{"or", `a || b`, []string{
+			"0000 OpGetGlobal 7",
+			"0003 OpJumpIfTrueOrPop 9",
+			"0006 OpGetGlobal 8",
+			"0009 OpReturn",
+		}},
+		{"for", `for x in [1] { x }`, []string{
+			"0000 OpConstant 0",
+			"0003 OpArray 1",
+			"0006 OpIter 1",
+			"0008 OpIterNext 21 1",
+			"0012 OpSetLocal 0",
+			"0014 OpPop",
+			"0015 OpGetLocal 0",
+			"0017 OpPop",
+			"0018 OpJump 8",
+			"0021 OpNil",
+			"0022 OpReturn",
+		}},
+		{"closure", `-> (a) { -> { a } }`, []string{
+			"0000 OpClosure 1 0",
+			"0004 OpReturn",
+		}},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			bc := compile(t, d.src)
+			want := strings.Join(d.want, "\n") + "\n"
+			if got := bc.Main.Instructions.String(); got != want {
+				t.Errorf("want\n%s\ngot\n%s", want, got)
+			}
+		})
+	}
+}
+
+func TestCompileUpvalues(t *testing.T) {
+	bc := compile(t, `-> (a) { -> { a } }`)
+	outer, ok := bc.Constants[1].(*compiler.CompiledFunction)
+	if !ok {
+		t.Fatalf("want <%T> got <%T>", outer, bc.Constants[1])
+	}
+	want := "0000 OpClosure 0 1 (1 0)\n0006 OpReturn\n"
+	if got := outer.Instructions.String(); got != want {
+		t.Errorf("want\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"break", `break`, "break outside of loop"},
+		{"continue in function", "while true {\n  -> { continue }\n}", "continue outside of loop"},
+		{"too many constants", strings.Repeat("x = 1\n", 70000), "too many constants"},
+		{"too many globals", manyGlobals(70000), "too many global variables"},
+		{"too many upvalues", manyUpvalues(256), "too many upvalues"},
+		{"jump forward too far", "if x {\n" + strings.Repeat("nil\n", 40000) + "}", "jump target out of range"},
+		{"jump back too far", strings.Repeat("nil\n", 40000) + "while x {}", "jump target out of range"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = compiler.Compile(tree)
+			if err == nil || !strings.Contains(err.Error(), d.want) {
+				t.Errorf("want <%s> got <%v>", d.want, err)
+			}
+		})
+	}
+}
+
+func manyGlobals(n int) string {
+	var b strings.Builder
+	for i := range n {
+		fmt.Fprintf(&b, "def x%d\n", i)
+	}
+	return b.String()
+}
+
+// manyUpvalues returns a closure capturing n variables, defined in as many
+// enclosing functions as the limit of their locals asks for.
+func manyUpvalues(n int) string {
+	var b strings.Builder
+	var uses []string
+	for i := 0; i < n; i += 200 {
+		b.WriteString("-> {\n")
+		for j := i; j < min(i+200, n); j++ {
+			fmt.Fprintf(&b, "def x%d\n", j)
+			uses = append(uses, fmt.Sprintf("x%d", j))
+		}
+	}
+	fmt.Fprintf(&b, "-> { [%s] }\n", strings.Join(uses, ", "))
+	b.WriteString(strings.Repeat("}\n", (n+199)/200))
+	return b.String()
+}
+
+func compile(t *testing.T, src string) *compiler.Bytecode {
+	t.Helper()
+	tree, err := parser.ParseString(src, "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := compiler.Compile(tree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bc
+}
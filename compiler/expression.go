@@ -0,0 +1,187 @@
+package compiler
+
+import (
+	"math"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/object"
+)
+
+func compileExpression(c *Compiler, x ast.Expression) error {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		return c.emitGet(x.Loc, x.Name)
+	case *ast.NilLiteral:
+		c.emit(x.Loc, OpNil)
+	case *ast.BoolLiteral:
+		if x.Value {
+			c.emit(x.Loc, OpTrue)
+		} else {
+			c.emit(x.Loc, OpFalse)
+		}
+	case *ast.IntLiteral:
+		return c.emitConstant(x.Loc, object.Int(x.Value))
+	case *ast.FloatLiteral:
+		return c.emitConstant(x.Loc, object.Float(x.Value))
+	case *ast.StringLiteral:
+		return c.emitConstant(x.Loc, object.String(x.Value))
+	case *ast.InterpolatedString:
+		for _, e := range x.Parts {
+			if err := compileExpression(c, e); err != nil {
+				return err
+			}
+		}
+		c.emit(x.Loc, OpConcat, len(x.Parts))
+	case *ast.ArrayLiteral:
+		for _, e := range x.Elements {
+			if err := compileExpression(c, e); err != nil {
+				return err
+			}
+		}
+		c.emit(x.Loc, OpArray, len(x.Elements))
+	case *ast.HashLiteral:
+		for _, p := range x.Pairs {
+			if err := compileExpression(c, p.Key); err != nil {
+				return err
+			}
+			if err := compileExpression(c, p.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(x.Loc, OpHash, len(x.Pairs))
+	case *ast.FunctionLiteral:
+		return compileFunctionLiteral(c, x)
+	case *ast.PrefixExpression:
+		if err := compileExpression(c, x.Right); err != nil {
+			return err
+		}
+		c.emit(x.Loc, OpPrefix, int(x.Operator))
+	case *ast.InfixExpression:
+		if err := compileExpression(c, x.Left); err != nil {
+			return err
+		}
+		if err := compileExpression(c, x.Right); err != nil {
+			return err
+		}
+		c.emit(x.Loc, OpInfix, int(x.Operator))
+	case *ast.LogicalExpression:
+		return compileLogical(c, x)
+	case *ast.If:
+		return compileIf(c, x)
+	case *ast.Else:
+		return compileBlock(c, x.Body)
+	case *ast.Call:
+		if err := compileExpression(c, x.Function); err != nil {
+			return err
+		}
+		for _, a := range x.Arguments {
+			if err := compileExpression(c, a); err != nil {
+				return err
+			}
+		}
+		if len(x.Arguments) > math.MaxUint8 {
+			return c.errorf(x.Loc, "too many arguments")
+		}
+		c.emit(x.Loc, OpCall, len(x.Arguments))
+	case *ast.KeyAccess:
+		if err := compileExpression(c, x.Container); err != nil {
+			return err
+		}
+		if err := compileExpression(c, x.Key); err != nil {
+			return err
+		}
+		c.emit(x.Loc, OpIndex)
+	case *ast.Let:
+		if err := compileExpression(c, x.Right); err != nil {
+			return err
+		}
+		return c.emitSet(x.Left.Loc, x.Left.Name)
+	case *ast.KeyAssign:
+		if err := compileExpression(c, x.Left.Container); err != nil {
+			return err
+		}
+		if err := compileExpression(c, x.Left.Key); err != nil {
+			return err
+		}
+		if err := compileExpression(c, x.Right); err != nil {
+			return err
+		}
+		c.emit(x.Loc, OpSetIndex)
+	case *ast.InvalidExpression:
+		return x.Err
+	default:
+		return c.errorf(x.Location(), "unsupported expression - %T", x)
+	}
+	return nil
+}
+
+func compileIf(c *Compiler, x *ast.If) error {
+	if err := compileExpression(c, x.Test); err != nil {
+		return err
+	}
+	alt := c.emit(x.Loc, OpJumpIfFalse, 0xffff)
+	if err := compileBlock(c, x.Body); err != nil {
+		return err
+	}
+	end := c.emit(x.Loc, OpJump, 0xffff)
+	if err := c.patchJump(alt); err != nil {
+		return err
+	}
+
+	// only one of the branches pushes its value
+	c.fn.depth--
+	if x.Alt == nil {
+		c.emit(x.Loc, OpNil)
+	} else if err := compileExpression(c, x.Alt); err != nil {
+		return err
+	}
+	return c.patchJump(end)
+}
+
+// compileLogical leaves the left operand as the value if it decides it,
+// and evaluates the right one otherwise.
+func compileLogical(c *Compiler, x *ast.LogicalExpression) error {
+	if err := compileExpression(c, x.Left); err != nil {
+		return err
+	}
+	op := OpJumpIfFalseOrPop
+	if x.Operator == ast.Or {
+		op = OpJumpIfTrueOrPop
+	}
+	end := c.emit(x.Loc, op, 0xffff)
+	if err := compileExpression(c, x.Right); err != nil {
+		return err
+	}
+	return c.patchJump(end)
+}
+
+func compileFunctionLiteral(c *Compiler, x *ast.FunctionLiteral) error {
+	fs := newFuncState(c.fn)
+	c.fn = fs
+	c.pushScope()
+	for _, p := range x.Parameters {
+		if _, err := c.declareLocal(p.Loc, p.Name); err != nil {
+			return err
+		}
+	}
+	if err := compileStatements(c, x.Statements); err != nil {
+		return err
+	}
+	c.emit(x.Loc, OpReturn)
+	c.fn = fs.enclosing
+
+	fn := fs.function(len(x.Parameters))
+	i, err := c.addConstant(x.Loc, fn)
+	if err != nil {
+		return err
+	}
+	c.emit(x.Loc, OpClosure, i, len(fs.upvalues))
+	for _, u := range fs.upvalues {
+		isLocal := 0
+		if u.isLocal {
+			isLocal = 1
+		}
+		c.fn.ins = append(c.fn.ins, byte(isLocal), byte(u.index))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package compiler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/arikui1911/goore/ast"
+)
+
+type Opcode byte
+
+//go:generate stringer -type=Opcode opcode.go
+const (
+	OpConstant Opcode = iota
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpPrefix
+	OpInfix
+	OpJump
+	OpJumpIfFalse
+	OpJumpIfFalseOrPop
+	OpJumpIfTrueOrPop
+	OpGetGlobal
+	OpSetGlobal
+	OpDefineGlobal
+	OpGetLocal
+	OpSetLocal
+	OpGetUpvalue
+	OpSetUpvalue
+	OpCloseUpvalues
+	OpArray
+	OpHash
+	OpIndex
+	OpSetIndex
+	OpCall
+	OpReturn
+	OpClosure
+	OpIter
+	OpIterNext
+	OpConcat
+)
+
+// operandWidths lists the byte width of each operand. OpClosure is
+// additionally followed by two bytes (isLocal, index) per captured upvalue.
+// OpIter and OpIterNext take the number of loop variables, and OpIterNext
+// pushes an entry of that many values or else jumps.
+var operandWidths = map[Opcode][]int{
+	OpConstant:         {2},
+	OpPrefix:           {1},
+	OpInfix:            {1},
+	OpJump:             {2},
+	OpJumpIfFalse:      {2},
+	OpJumpIfFalseOrPop: {2},
+	OpJumpIfTrueOrPop:  {2},
+	OpGetGlobal:        {2},
+	OpSetGlobal:        {2},
+	OpDefineGlobal:     {2},
+	OpGetLocal:         {1},
+	OpSetLocal:         {1},
+	OpGetUpvalue:       {1},
+	OpSetUpvalue:       {1},
+	OpCloseUpvalues:    {1},
+	OpArray:            {2},
+	OpHash:             {2},
+	OpCall:             {1},
+	OpClosure:          {2, 1},
+	OpIter:             {1},
+	OpIterNext:         {2, 1},
+	OpConcat:           {2},
+}
+
+type Instructions []byte
+
+func Make(op Opcode, operands ...int) []byte {
+	widths := operandWidths[op]
+	ins := []byte{byte(op)}
+	for i, o := range operands {
+		switch widths[i] {
+		case 1:
+			ins = append(ins, byte(o))
+		case 2:
+			ins = binary.BigEndian.AppendUint16(ins, uint16(o))
+		}
+	}
+	return ins
+}
+
+func ReadUint16(ins Instructions, offset int) int {
+	return int(binary.BigEndian.Uint16(ins[offset:]))
+}
+
+func ReadUint8(ins Instructions, offset int) int {
+	return int(ins[offset])
+}
+
+func (ins Instructions) String() string {
+	var b strings.Builder
+	for i := 0; i < len(ins); {
+		op := Opcode(ins[i])
+		fmt.Fprintf(&b, "%04d %s", i, op)
+		i++
+		operands := []int{}
+		for _, w := range operandWidths[op] {
+			switch w {
+			case 1:
+				operands = append(operands, ReadUint8(ins, i))
+			case 2:
+				operands = append(operands, ReadUint16(ins, i))
+			}
+			i += w
+		}
+		switch op {
+		case OpPrefix, OpInfix:
+			fmt.Fprintf(&b, " %v", ast.Operation(operands[0]))
+		default:
+			for _, o := range operands {
+				fmt.Fprintf(&b, " %d", o)
+			}
+		}
+		if op == OpClosure {
+			for n := 0; n < operands[1]; n++ {
+				fmt.Fprintf(&b, " (%d %d)", ins[i], ins[i+1])
+				i += 2
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,212 @@
+package compiler
+
+import (
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/token"
+)
+
+// compileStatements leaves the value of the last statement on the stack,
+// or nil when it is not an expression statement.
+func compileStatements(c *Compiler, stmts []ast.Statement) error {
+	if len(stmts) == 0 {
+		c.emit(nil, OpNil)
+		return nil
+	}
+	if err := c.declareDefs(stmts); err != nil {
+		return err
+	}
+	for i, s := range stmts {
+		pushed, err := compileStatement(c, s)
+		if err != nil {
+			return err
+		}
+		last := i == len(stmts)-1
+		if pushed && !last {
+			c.emit(nil, OpPop)
+		}
+		if !pushed && last {
+			c.emit(nil, OpNil)
+		}
+	}
+	return nil
+}
+
+func compileBlock(c *Compiler, stmts []ast.Statement) error {
+	c.pushScope()
+	if err := compileStatements(c, stmts); err != nil {
+		return err
+	}
+	c.popScope()
+	return nil
+}
+
+func compileStatement(c *Compiler, s ast.Statement) (bool, error) {
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		return true, compileExpression(c, s.Expression)
+	case *ast.Def:
+		return false, compileDef(c, s)
+	case *ast.While:
+		return false, compileWhile(c, s)
+	case *ast.For:
+		return false, compileFor(c, s)
+	case *ast.Break:
+		return false, compileJumpOut(c, s.Loc, "break")
+	case *ast.Continue:
+		return false, compileJumpOut(c, s.Loc, "continue")
+	case *ast.Return:
+		if s.Expression == nil {
+			c.emit(s.Loc, OpNil)
+		} else if err := compileExpression(c, s.Expression); err != nil {
+			return false, err
+		}
+		c.emit(s.Loc, OpReturn)
+		return false, nil
+	case *ast.InvalidStatement:
+		return false, s.Err
+	default:
+		return false, c.errorf(s.Location(), "unsupported statement - %T", s)
+	}
+}
+
+func compileDef(c *Compiler, s *ast.Def) error {
+	compileInit := func() error {
+		if s.Init == nil {
+			c.emit(s.Loc, OpNil)
+			return nil
+		}
+		return compileExpression(c, s.Init)
+	}
+
+	if c.isTopLevel() {
+		if err := compileInit(); err != nil {
+			return err
+		}
+		i, err := c.globalIndex(s.Name.Loc, s.Name.Name)
+		if err != nil {
+			return err
+		}
+		c.emit(s.Loc, OpDefineGlobal, i)
+		return nil
+	}
+
+	// The local is declared ahead already. A function literal may refer to
+	// itself, so its name is defined before the body is compiled. Other
+	// initializers still see an outer variable of the same name.
+	l := c.fn.scopes[len(c.fn.scopes)-1].locals[s.Name.Name]
+	if _, ok := s.Init.(*ast.FunctionLiteral); ok {
+		l.ahead = false
+		if err := compileInit(); err != nil {
+			return err
+		}
+	} else {
+		if err := compileInit(); err != nil {
+			return err
+		}
+		l.ahead = false
+	}
+	c.emit(s.Loc, OpSetLocal, l.slot)
+	c.emit(s.Loc, OpPop)
+	return nil
+}
+
+func compileWhile(c *Compiler, s *ast.While) error {
+	fs := c.fn
+	start := len(fs.ins)
+	if err := compileExpression(c, s.Cond); err != nil {
+		return err
+	}
+	exit := c.emit(s.Loc, OpJumpIfFalse, 0xffff)
+
+	loop := &loopState{start: start, depth: fs.depth, scopes: len(fs.scopes)}
+	fs.loops = append(fs.loops, loop)
+	if err := compileBlock(c, s.Body); err != nil {
+		return err
+	}
+	fs.loops = fs.loops[:len(fs.loops)-1]
+
+	c.emit(s.Loc, OpPop)
+	if err := c.emitJump(s.Loc, start); err != nil {
+		return err
+	}
+	return c.patchExits(exit, loop.breaks)
+}
+
+// patchExits makes the exit of a loop and its breaks jump behind it.
+func (c *Compiler) patchExits(exit int, breaks []int) error {
+	if err := c.patchJump(exit); err != nil {
+		return err
+	}
+	for _, b := range breaks {
+		if err := c.patchJump(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compileFor keeps the iterator on the stack through the loop. Each entry
+// is stored to the loop variables, which are locals of the body's scope.
+func compileFor(c *Compiler, s *ast.For) error {
+	fs := c.fn
+	if err := compileExpression(c, s.Iterable); err != nil {
+		return err
+	}
+	c.emit(s.Iterable.Location(), OpIter, len(s.Vars))
+	start := len(fs.ins)
+	exit := c.emit(s.Loc, OpIterNext, 0xffff, len(s.Vars))
+
+	loop := &loopState{start: start, depth: fs.depth - len(s.Vars), temps: 1, scopes: len(fs.scopes)}
+	fs.loops = append(fs.loops, loop)
+	c.pushScope()
+	for i := len(s.Vars) - 1; i >= 0; i-- {
+		slot, err := c.declareLocal(s.Vars[i].Loc, s.Vars[i].Name)
+		if err != nil {
+			return err
+		}
+		c.emit(s.Vars[i].Loc, OpSetLocal, slot)
+		c.emit(s.Vars[i].Loc, OpPop)
+	}
+	if err := compileStatements(c, s.Body); err != nil {
+		return err
+	}
+	c.emit(s.Loc, OpPop)
+	c.popScope()
+	fs.loops = fs.loops[:len(fs.loops)-1]
+
+	if err := c.emitJump(s.Loc, start); err != nil {
+		return err
+	}
+	if err := c.patchExits(exit, loop.breaks); err != nil {
+		return err
+	}
+	// the iterator is gone behind the loop
+	fs.depth--
+	return nil
+}
+
+// compileJumpOut emits break or continue: it drops the temporaries pushed
+// since the loop began and closes upvalues of the block scopes it leaves.
+func compileJumpOut(c *Compiler, loc *token.Location, kw string) error {
+	fs := c.fn
+	if len(fs.loops) == 0 {
+		return c.errorf(loc, "%s outside of loop", kw)
+	}
+	loop := fs.loops[len(fs.loops)-1]
+
+	depth := fs.depth
+	for i := loop.depth; i < depth; i++ {
+		c.emit(loc, OpPop)
+	}
+	c.emit(loc, OpCloseUpvalues, fs.scopes[loop.scopes].base)
+	if kw == "break" {
+		for range loop.temps {
+			c.emit(loc, OpPop)
+		}
+		loop.breaks = append(loop.breaks, c.emit(loc, OpJump, 0xffff))
+	} else if err := c.emitJump(loc, loop.start); err != nil {
+		return err
+	}
+	fs.depth = depth
+	return nil
+}
@@ -0,0 +1,142 @@
+package diag
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/arikui1911/goore/token"
+)
+
+type Severity int
+
+const (
+	Error Severity = iota
+	Warning
+	Note
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "error"
+	case Warning:
+		return "warning"
+	case Note:
+		return "note"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	for _, v := range []Severity{Error, Warning, Note} {
+		if v.String() == string(text) {
+			*s = v
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown severity %q", text)
+}
+
+// Code identifies the kind of a diagnostic independently of its message.
+type Code string
+
+const (
+	UnexpectedToken    Code = "unexpected-token"
+	UnclosedBlock      Code = "unclosed-block"
+	MissingComma       Code = "missing-comma"
+	InvalidAssignment  Code = "invalid-assignment"
+	InvalidNumber      Code = "invalid-number"
+	InvalidCharacter   Code = "invalid-character"
+	UnterminatedString Code = "unterminated-string"
+	InvalidEscape      Code = "invalid-escape"
+	InvalidMultiline   Code = "invalid-multiline"
+	MissingSemicolon   Code = "missing-semicolon"
+	TrailingComma      Code = "trailing-comma"
+	IfAsValue          Code = "if-as-value"
+	JumpOutsideLoop    Code = "jump-outside-loop"
+)
+
+// Fix is an edit suggested to resolve a diagnostic. NewText replaces the
+// text at Loc, or goes right behind it when Insert is set.
+type Fix struct {
+	Message string         `json:"message"`
+	Loc     token.Location `json:"loc"`
+	Insert  bool           `json:"insert,omitempty"`
+	NewText string         `json:"newText"`
+}
+
+// Diagnostic is a problem found in a source file. It is an error so it can
+// travel the usual error paths; Error formats it as the compilers always
+// did, file name first when known.
+type Diagnostic struct {
+	FileName string         `json:"fileName,omitempty"`
+	Loc      token.Location `json:"loc"`
+	Severity Severity       `json:"severity"`
+	Code     Code           `json:"code,omitempty"`
+	Message  string         `json:"message"`
+	Fix      *Fix           `json:"fix,omitempty"`
+}
+
+func (d *Diagnostic) Error() string {
+	if d.FileName == "" {
+		return fmt.Sprintf("%s: %s", d.Loc, d.Message)
+	}
+	return fmt.Sprintf("%s:%s: %s", d.FileName, d.Loc, d.Message)
+}
+
+// List is a list of diagnostics. As an error it reads one diagnostic per
+// line.
+type List []*Diagnostic
+
+func (l List) Error() string {
+	msgs := make([]string, len(l))
+	for i, d := range l {
+		msgs[i] = d.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (l List) Unwrap() []error {
+	errs := make([]error, len(l))
+	for i, d := range l {
+		errs[i] = d
+	}
+	return errs
+}
+
+// Err returns l as an error, or nil when l is empty.
+func (l List) Err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return l
+}
+
+// Sort sorts l by file name and then by location, keeping the order of
+// diagnostics at the same place.
+func (l List) Sort() {
+	slices.SortStableFunc(l, func(a *Diagnostic, b *Diagnostic) int {
+		return cmp.Or(
+			cmp.Compare(a.FileName, b.FileName),
+			cmp.Compare(a.Loc.StartLine, b.Loc.StartLine),
+			cmp.Compare(a.Loc.StartColumn, b.Loc.StartColumn),
+		)
+	})
+}
+
+// Filter returns the diagnostics of l for which keep returns true.
+func (l List) Filter(keep func(*Diagnostic) bool) List {
+	var r List
+	for _, d := range l {
+		if keep(d) {
+			r = append(r, d)
+		}
+	}
+	return r
+}
@@ -0,0 +1,69 @@
+package diag_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/token"
+)
+
+func at(fileName string, line int, col int, msg string) *diag.Diagnostic {
+	return &diag.Diagnostic{
+		FileName: fileName,
+		Loc:      token.Location{StartLine: line, StartColumn: col, EndLine: line, EndColumn: col},
+		Message:  msg,
+	}
+}
+
+func TestDiagnosticError(t *testing.T) {
+	table := []struct {
+		name string
+		d    *diag.Diagnostic
+		want string
+	}{
+		{"with file name", at("a.goore", 1, 2, "oops"), "a.goore:(1:2):(1:2): oops"},
+		{"without file name", at("", 3, 4, "oops"), "(3:4):(3:4): oops"},
+	}
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			if got := d.d.Error(); got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+		})
+	}
+}
+
+func TestListSort(t *testing.T) {
+	l := diag.List{at("b", 1, 1, "4"), at("a", 2, 1, "2"), at("a", 1, 5, "1"), at("a", 2, 1, "3")}
+	l.Sort()
+	for i, d := range l {
+		if want := string(rune('1' + i)); d.Message != want {
+			t.Errorf("want <%v> got <%v>", want, d.Message)
+		}
+	}
+}
+
+func TestListErr(t *testing.T) {
+	if err := (diag.List{}).Err(); err != nil {
+		t.Errorf("want <nil> got <%v>", err)
+	}
+	l := diag.List{at("a", 1, 1, "x"), at("a", 2, 1, "y")}
+	err := l.Err()
+	if want := "a:(1:1):(1:1): x\na:(2:1):(2:1): y"; err.Error() != want {
+		t.Errorf("want <%v> got <%v>", want, err.Error())
+	}
+	var d *diag.Diagnostic
+	if !errors.As(err, &d) || d != l[0] {
+		t.Errorf("want <%v> got <%v>", l[0], d)
+	}
+}
+
+func TestListFilter(t *testing.T) {
+	l := diag.List{at("a", 1, 1, "x"), at("a", 2, 1, "y")}
+	l[1].Severity = diag.Warning
+	got := l.Filter(func(d *diag.Diagnostic) bool { return d.Severity == diag.Warning })
+	if len(got) != 1 || got[0] != l[1] {
+		t.Errorf("want <%v> got <%v>", l[1:], got)
+	}
+}
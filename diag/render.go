@@ -0,0 +1,193 @@
+package diag
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Printer renders diagnostics together with the source lines they point
+// at, underlining the offending columns:
+//
+//	error[unexpected-token]: unexpected token - "1"(IntLiteral) expect identifier
+//	 --> test.goore:1:5
+//	  |
+//	1 | def 1
+//	  |     ^
+//
+// A span over several lines is drawn along the left of its lines.
+type Printer struct {
+	// Color enables ANSI escape sequences.
+	Color bool
+	// TabWidth is the number of columns a tab is shown as, 4 if zero.
+	TabWidth int
+	// Context is the number of lines shown at each end of a span over
+	// several lines before the rest is elided, 2 if zero.
+	Context int
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+)
+
+var severityColors = map[Severity]string{
+	Error:   ansiRed,
+	Warning: ansiYellow,
+	Note:    ansiCyan,
+}
+
+// Fprint renders every diagnostic err holds, as a single Diagnostic or
+// wrapped in a List, and writes any other error as a plain line.
+func (p *Printer) Fprint(w io.Writer, src []byte, err error) error {
+	var l List
+	var d *Diagnostic
+	switch {
+	case errors.As(err, &l):
+	case errors.As(err, &d):
+		l = List{d}
+	default:
+		_, werr := fmt.Fprintln(w, strings.TrimRight(err.Error(), "\n"))
+		return werr
+	}
+	lines := strings.Split(string(src), "\n")
+	for i, d := range l {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, p.render(lines, d)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Printer) paint(s string, codes ...string) string {
+	if !p.Color {
+		return s
+	}
+	return strings.Join(codes, "") + s + ansiReset
+}
+
+func (p *Printer) tabWidth() int {
+	if p.TabWidth > 0 {
+		return p.TabWidth
+	}
+	return 4
+}
+
+func (p *Printer) context() int {
+	if p.Context > 0 {
+		return p.Context
+	}
+	return 2
+}
+
+// expand returns line with tabs expanded and the display column where each
+// rune column starts; cols has one more entry for the column behind the
+// last rune.
+func (p *Printer) expand(line string) (string, []int) {
+	var b strings.Builder
+	cols := []int{0}
+	n := 0
+	for _, r := range line {
+		if r == '\t' {
+			w := p.tabWidth() - n%p.tabWidth()
+			b.WriteString(strings.Repeat(" ", w))
+			n += w
+		} else {
+			b.WriteRune(r)
+			n++
+		}
+		cols = append(cols, n)
+	}
+	return b.String(), cols
+}
+
+// column returns the display column of the one based rune column col,
+// extending past the end of the line one column per rune.
+func column(cols []int, col int) int {
+	i := max(col-1, 0)
+	if i < len(cols) {
+		return cols[i]
+	}
+	return cols[len(cols)-1] + i - (len(cols) - 1)
+}
+
+func (p *Printer) render(lines []string, d *Diagnostic) string {
+	var b strings.Builder
+	color := severityColors[d.Severity]
+	head := d.Severity.String()
+	if d.Code != "" {
+		head += "[" + string(d.Code) + "]"
+	}
+	fmt.Fprintf(&b, "%s%s\n", p.paint(head, ansiBold, color), p.paint(": "+d.Message, ansiBold))
+
+	loc := d.Loc
+	width := len(strconv.Itoa(loc.EndLine))
+	gutter := func(n int) string {
+		s := strings.Repeat(" ", width)
+		if n > 0 {
+			s = fmt.Sprintf("%*d", width, n)
+		}
+		return p.paint(s+" |", ansiBold, ansiBlue)
+	}
+	text := func(n int) (string, []int) {
+		if n < 1 || n > len(lines) {
+			return "", []int{0}
+		}
+		return p.expand(lines[n-1])
+	}
+
+	if d.FileName != "" {
+		fmt.Fprintf(&b, "%s %s:%d:%d\n", p.paint(strings.Repeat(" ", width)+"-->", ansiBold, ansiBlue), d.FileName, loc.StartLine, loc.StartColumn)
+	}
+	fmt.Fprintf(&b, "%s\n", gutter(0))
+
+	if loc.StartLine == loc.EndLine {
+		s, cols := text(loc.StartLine)
+		beg := column(cols, loc.StartColumn)
+		end := max(column(cols, loc.EndColumn+1), beg+1)
+		fmt.Fprintf(&b, "%s %s\n", gutter(loc.StartLine), s)
+		fmt.Fprintf(&b, "%s %s%s\n", gutter(0), strings.Repeat(" ", beg), p.paint(strings.Repeat("^", end-beg), ansiBold, color))
+	} else {
+		bar := p.paint("|", ansiBold, color)
+		s, cols := text(loc.StartLine)
+		fmt.Fprintf(&b, "%s   %s\n", gutter(loc.StartLine), s)
+		fmt.Fprintf(&b, "%s %s\n", gutter(0), p.paint(strings.Repeat("_", column(cols, loc.StartColumn)+2)+"^", ansiBold, color))
+		ctx := p.context()
+		for n := loc.StartLine + 1; n <= loc.EndLine; n++ {
+			if loc.EndLine-loc.StartLine > 2*ctx+1 && n == loc.StartLine+ctx+1 {
+				fmt.Fprintf(&b, "%s %s\n", p.paint(strings.Repeat(".", width+2), ansiBold, ansiBlue), bar)
+				n = loc.EndLine - ctx
+			}
+			s, _ := text(n)
+			fmt.Fprintf(&b, "%s %s %s\n", gutter(n), bar, s)
+		}
+		_, cols = text(loc.EndLine)
+		fmt.Fprintf(&b, "%s %s\n", gutter(0), p.paint("|"+strings.Repeat("_", column(cols, loc.EndColumn)+1)+"^", ansiBold, color))
+	}
+
+	if f := d.Fix; f != nil {
+		edit := fmt.Sprintf("replace %s with %q", f.Loc, f.NewText)
+		if f.Insert {
+			edit = fmt.Sprintf("insert %q after %d:%d", f.NewText, f.Loc.EndLine, f.Loc.EndColumn)
+		}
+		fmt.Fprintf(&b, "%s %s: %s - %s\n", p.paint(strings.Repeat(" ", width)+" =", ansiBold, ansiBlue), p.paint("help", ansiBold), f.Message, edit)
+	}
+
+	// blank source lines leave trailing spaces behind the gutter
+	out := strings.Split(b.String(), "\n")
+	for i, line := range out {
+		out[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,99 @@
+package diag_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/token"
+)
+
+func span(sl int, sc int, el int, ec int) token.Location {
+	return token.Location{StartLine: sl, StartColumn: sc, EndLine: el, EndColumn: ec}
+}
+
+func TestPrinter(t *testing.T) {
+	table := []struct {
+		name string
+		p    diag.Printer
+		src  string
+		err  error
+		want string
+	}{
+		{
+			"single line",
+			diag.Printer{},
+			"def x = 1\nx + yy\n",
+			&diag.Diagnostic{FileName: "a.goore", Loc: span(2, 5, 2, 6), Code: diag.UnexpectedToken, Message: "oops"},
+			"error[unexpected-token]: oops\n --> a.goore:2:5\n  |\n2 | x + yy\n  |     ^^\n",
+		},
+		{
+			"tabs",
+			diag.Printer{TabWidth: 2},
+			"\tx\n",
+			&diag.Diagnostic{Loc: span(1, 2, 1, 2), Severity: diag.Warning, Message: "oops"},
+			"warning: oops\n  |\n1 |   x\n  |   ^\n",
+		},
+		{
+			"multiple lines",
+			diag.Printer{},
+			"while x {\n1\n",
+			&diag.Diagnostic{Loc: span(1, 9, 3, 1), Message: "oops"},
+			"error: oops\n  |\n1 |   while x {\n  | __________^\n2 | | 1\n3 | |\n  | |_^\n",
+		},
+		{
+			"elided lines",
+			diag.Printer{Context: 1},
+			"{\n1\n2\n3\n4\n}\n",
+			&diag.Diagnostic{Loc: span(1, 1, 6, 1), Message: "oops"},
+			"error: oops\n  |\n1 |   {\n  | __^\n2 | | 1\n... |\n5 | | 4\n6 | | }\n  | |_^\n",
+		},
+		{
+			"fix",
+			diag.Printer{},
+			"[1 2]\n",
+			&diag.Diagnostic{Loc: span(1, 4, 1, 4), Message: "oops", Fix: &diag.Fix{Message: "add", Loc: span(1, 2, 1, 2), Insert: true, NewText: ","}},
+			"error: oops\n  |\n1 | [1 2]\n  |    ^\n  = help: add - insert \",\" after 1:2\n",
+		},
+		{
+			"list",
+			diag.Printer{},
+			"ab\n",
+			diag.List{
+				{Loc: span(1, 1, 1, 1), Message: "a"},
+				{Loc: span(1, 2, 1, 2), Message: "b"},
+			}.Err(),
+			"error: a\n  |\n1 | ab\n  | ^\n\nerror: b\n  |\n1 | ab\n  |  ^\n",
+		},
+		{
+			"color",
+			diag.Printer{Color: true},
+			"x\n",
+			&diag.Diagnostic{Loc: span(1, 1, 1, 1), Message: "oops"},
+			"\x1b[1m\x1b[31merror\x1b[0m\x1b[1m: oops\x1b[0m\n\x1b[1m\x1b[34m  |\x1b[0m\n\x1b[1m\x1b[34m1 |\x1b[0m x\n\x1b[1m\x1b[34m  |\x1b[0m \x1b[1m\x1b[31m^\x1b[0m\n",
+		},
+		{
+			"other error",
+			diag.Printer{},
+			"x\n",
+			errors.New("oops\n"),
+			"oops\n",
+		},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := d.p.Fprint(&buf, []byte(d.src), d.err); err != nil {
+				t.Error(err)
+				return
+			}
+			if got := buf.String(); got != d.want {
+				t.Errorf("want <%#v> got <%#v>", d.want, got)
+				t.Log("\n" + strings.ReplaceAll(got, "\x1b", "ESC"))
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/object"
+	"github.com/arikui1911/goore/token"
+)
+
+// MaxFrames limits the function calls in progress, counting the top level
+// as the first one like the frames of the vm do.
+const MaxFrames = 1024
+
+type Interpreter struct {
+	globals  *object.Environment
+	fileName string
+	// calls counts the function calls in progress.
+	calls int
+}
+
+func New(out io.Writer) *Interpreter {
+	return &Interpreter{
+		globals: object.NewEnvironment(newBuiltins(out)),
+	}
+}
+
+func newBuiltins(out io.Writer) *object.Environment {
+	env := object.NewEnvironment(nil)
+	for _, b := range object.Builtins(out) {
+		env.Define(b.Name, b)
+	}
+	return env
+}
+
+func (in *Interpreter) Globals() *object.Environment {
+	return in.globals
+}
+
+func (in *Interpreter) Run(prog *ast.Program) (object.Object, error) {
+	if prog.Err != nil {
+		return nil, prog.Err
+	}
+	in.fileName = prog.FileName
+	v, err := evalStatements(in, prog.Statements, in.globals)
+	if err != nil {
+		switch sig := err.(type) {
+		case *returnSignal:
+			return sig.value, nil
+		case *breakSignal:
+			return nil, in.errorf(sig.loc, "break outside of loop")
+		case *continueSignal:
+			return nil, in.errorf(sig.loc, "continue outside of loop")
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+func (in *Interpreter) errorf(loc *token.Location, format string, args ...any) error {
+	return fmt.Errorf("%s:%s: %s", in.fileName, loc, fmt.Sprintf(format, args...))
+}
+
+// Break, continue and return unwind the Go stack as errors until the
+// enclosing loop or function call catches them.
+
+type breakSignal struct {
+	loc *token.Location
+}
+
+func (*breakSignal) Error() string { return "break outside of loop" }
+
+type continueSignal struct {
+	loc *token.Location
+}
+
+func (*continueSignal) Error() string { return "continue outside of loop" }
+
+type returnSignal struct {
+	loc   *token.Location
+	value object.Object
+}
+
+func (*returnSignal) Error() string { return "return outside of function" }
+
+func evalStatements(in *Interpreter, stmts []ast.Statement, env *object.Environment) (object.Object, error) {
+	var result object.Object = object.Nil{}
+	for _, s := range stmts {
+		v, err := evalStatement(in, s, env)
+		if err != nil {
+			return nil, err
+		}
+		result = v
+	}
+	return result, nil
+}
+
+func evalStatement(in *Interpreter, s ast.Statement, env *object.Environment) (object.Object, error) {
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		return evalExpression(in, s.Expression, env)
+	case *ast.Def:
+		var v object.Object = object.Nil{}
+		if s.Init != nil {
+			x, err := evalExpression(in, s.Init, env)
+			if err != nil {
+				return nil, err
+			}
+			v = x
+		}
+		env.Define(s.Name.Name, v)
+		return object.Nil{}, nil
+	case *ast.While:
+		return evalWhile(in, s, env)
+	case *ast.For:
+		return evalFor(in, s, env)
+	case *ast.Break:
+		return nil, &breakSignal{loc: s.Loc}
+	case *ast.Continue:
+		return nil, &continueSignal{loc: s.Loc}
+	case *ast.Return:
+		var v object.Object = object.Nil{}
+		if s.Expression != nil {
+			x, err := evalExpression(in, s.Expression, env)
+			if err != nil {
+				return nil, err
+			}
+			v = x
+		}
+		return nil, &returnSignal{loc: s.Loc, value: v}
+	case *ast.InvalidStatement:
+		return nil, s.Err
+	default:
+		return nil, in.errorf(s.Location(), "unsupported statement - %T", s)
+	}
+}
+
+func evalWhile(in *Interpreter, s *ast.While, env *object.Environment) (object.Object, error) {
+	for {
+		cond, err := evalExpression(in, s.Cond, env)
+		if err != nil {
+			return nil, err
+		}
+		if !object.Truthy(cond) {
+			return object.Nil{}, nil
+		}
+		_, err = evalStatements(in, s.Body, object.NewEnvironment(env))
+		switch err.(type) {
+		case nil, *continueSignal:
+		case *breakSignal:
+			return object.Nil{}, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+// evalFor runs the body in a scope of its own for each entry, so closures
+// made in it keep the variables of their iteration.
+func evalFor(in *Interpreter, s *ast.For, env *object.Environment) (object.Object, error) {
+	x, err := evalExpression(in, s.Iterable, env)
+	if err != nil {
+		return nil, err
+	}
+	iterable, ok := x.(object.Iterable)
+	if !ok {
+		return nil, in.errorf(s.Iterable.Location(), "not iterable - %s", x.Type())
+	}
+	it := iterable.Iterate(len(s.Vars) == 2)
+	for {
+		k, v, ok := it.Next()
+		if !ok {
+			return object.Nil{}, nil
+		}
+		scope := object.NewEnvironment(env)
+		if len(s.Vars) == 2 {
+			scope.Define(s.Vars[0].Name, k)
+		}
+		scope.Define(s.Vars[len(s.Vars)-1].Name, v)
+		_, err = evalStatements(in, s.Body, scope)
+		switch err.(type) {
+		case nil, *continueSignal:
+		case *breakSignal:
+			return object.Nil{}, nil
+		default:
+			return nil, err
+		}
+	}
+}
+
+func evalExpression(in *Interpreter, x ast.Expression, env *object.Environment) (object.Object, error) {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		v, ok := env.Get(x.Name)
+		if !ok {
+			return nil, in.errorf(x.Loc, "undefined variable - %s", x.Name)
+		}
+		return v, nil
+	case *ast.NilLiteral:
+		return object.Nil{}, nil
+	case *ast.BoolLiteral:
+		return object.Bool(x.Value), nil
+	case *ast.IntLiteral:
+		return object.Int(x.Value), nil
+	case *ast.FloatLiteral:
+		return object.Float(x.Value), nil
+	case *ast.StringLiteral:
+		return object.String(x.Value), nil
+	case *ast.InterpolatedString:
+		parts, err := evalExpressions(in, x.Parts, env)
+		if err != nil {
+			return nil, err
+		}
+		return object.Concat(parts), nil
+	case *ast.ArrayLiteral:
+		elems, err := evalExpressions(in, x.Elements, env)
+		if err != nil {
+			return nil, err
+		}
+		return &object.Array{Elements: elems}, nil
+	case *ast.HashLiteral:
+		return evalHashLiteral(in, x, env)
+	case *ast.FunctionLiteral:
+		return &object.Function{Parameters: x.Parameters, Body: x.Statements, Env: env}, nil
+	case *ast.PrefixExpression:
+		return evalPrefixExpression(in, x, env)
+	case *ast.InfixExpression:
+		return evalInfixExpression(in, x, env)
+	case *ast.LogicalExpression:
+		return evalLogicalExpression(in, x, env)
+	case *ast.If:
+		return evalIf(in, x, env)
+	case *ast.Else:
+		return evalStatements(in, x.Body, object.NewEnvironment(env))
+	case *ast.Call:
+		return evalCall(in, x, env)
+	case *ast.KeyAccess:
+		return evalKeyAccess(in, x, env)
+	case *ast.Let:
+		v, err := evalExpression(in, x.Right, env)
+		if err != nil {
+			return nil, err
+		}
+		if !env.Assign(x.Left.Name, v) {
+			return nil, in.errorf(x.Left.Loc, "undefined variable - %s", x.Left.Name)
+		}
+		return v, nil
+	case *ast.KeyAssign:
+		return evalKeyAssign(in, x, env)
+	case *ast.InvalidExpression:
+		return nil, x.Err
+	default:
+		return nil, in.errorf(x.Location(), "unsupported expression - %T", x)
+	}
+}
+
+func evalExpressions(in *Interpreter, xs []ast.Expression, env *object.Environment) ([]object.Object, error) {
+	vals := make([]object.Object, len(xs))
+	for i, x := range xs {
+		v, err := evalExpression(in, x, env)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func evalHashLiteral(in *Interpreter, x *ast.HashLiteral, env *object.Environment) (object.Object, error) {
+	h := object.NewHash()
+	for _, e := range x.Pairs {
+		k, err := evalExpression(in, e.Key, env)
+		if err != nil {
+			return nil, err
+		}
+		hk, ok := k.(object.Hashable)
+		if !ok {
+			return nil, in.errorf(x.Loc, "unhashable key - %s", k.Type())
+		}
+		v, err := evalExpression(in, e.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		h.Set(hk, v)
+	}
+	return h, nil
+}
+
+func evalIf(in *Interpreter, x *ast.If, env *object.Environment) (object.Object, error) {
+	test, err := evalExpression(in, x.Test, env)
+	if err != nil {
+		return nil, err
+	}
+	if object.Truthy(test) {
+		return evalStatements(in, x.Body, object.NewEnvironment(env))
+	}
+	if x.Alt == nil {
+		return object.Nil{}, nil
+	}
+	return evalExpression(in, x.Alt, env)
+}
+
+func evalCall(in *Interpreter, x *ast.Call, env *object.Environment) (object.Object, error) {
+	fn, err := evalExpression(in, x.Function, env)
+	if err != nil {
+		return nil, err
+	}
+	args, err := evalExpressions(in, x.Arguments, env)
+	if err != nil {
+		return nil, err
+	}
+	switch fn := fn.(type) {
+	case *object.Function:
+		return applyFunction(in, x, fn, args)
+	case *object.Builtin:
+		v, err := fn.Fn(args)
+		if err != nil {
+			return nil, in.errorf(x.Loc, "%s: %v", fn.Name, err)
+		}
+		return v, nil
+	default:
+		return nil, in.errorf(x.Loc, "not callable - %s", fn.Type())
+	}
+}
+
+func applyFunction(in *Interpreter, x *ast.Call, fn *object.Function, args []object.Object) (object.Object, error) {
+	if len(args) != len(fn.Parameters) {
+		return nil, in.errorf(x.Loc, "wrong number of arguments (given %d, expected %d)", len(args), len(fn.Parameters))
+	}
+	if in.calls+1 >= MaxFrames {
+		return nil, in.errorf(x.Loc, "stack overflow")
+	}
+	env := object.NewEnvironment(fn.Env)
+	for i, p := range fn.Parameters {
+		env.Define(p.Name, args[i])
+	}
+	in.calls++
+	v, err := evalStatements(in, fn.Body, env)
+	in.calls--
+	if err != nil {
+		switch sig := err.(type) {
+		case *returnSignal:
+			return sig.value, nil
+		case *breakSignal:
+			return nil, in.errorf(sig.loc, "break outside of loop")
+		case *continueSignal:
+			return nil, in.errorf(sig.loc, "continue outside of loop")
+		}
+		return nil, err
+	}
+	return v, nil
+}
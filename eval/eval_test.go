@@ -0,0 +1,165 @@
+package eval_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/eval"
+	"github.com/arikui1911/goore/object"
+	"github.com/arikui1911/goore/parser"
+)
+
+func run(t *testing.T, src string) (object.Object, string, error) {
+	t.Helper()
+	tree, err := parser.ParseString(src, "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	v, err := eval.New(&out).Run(tree)
+	return v, out.String(), err
+}
+
+func TestEvalExpressions(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"nil", `nil`, "nil"},
+		{"true", `true`, "true"},
+		{"int", `123`, "123"},
+		{"float", `1.5`, "1.5"},
+		{"string", `"Hello."`, "Hello."},
+		{"minus", `-3`, "-3"},
+		{"not", `!nil`, "true"},
+		{"arithmetic", `1 + 2 * 3 - 4 / 2`, "5"},
+		{"mod", `7 % 3`, "1"},
+		{"mixed", `1 + 0.5`, "1.5"},
+		{"compare", `1 < 2`, "true"},
+		{"equality", `1 == 1.0`, "true"},
+		{"string concat", `"foo" + "bar"`, "foobar"},
+		{"and", `[true && 1, nil && 1, 1 && false]`, "[1, nil, false]"},
+		{"or", `[false || 1, 2 || 3, nil || false]`, "[1, 2, false]"},
+		{"logical precedence", `1 < 2 && 3 == 3 || x`, "true"},
+		{"short circuit", `false && x || true || y`, "true"},
+		{"array", `[1, "a", nil]`, `[1, "a", nil]`},
+		{"array equality", `[1, [2]] == [1, [2]]`, "true"},
+		{"hash", `{"a": 1, 2: [3]}`, `{"a": 1, 2: [3]}`},
+		{"key access", `[10, 20, 30][1]`, "20"},
+		{"hash access", `{"a": 1}["a"]`, "1"},
+		{"missing key", `{"a": 1}["b"]`, "nil"},
+		{"if", `if 1 < 2 { "yes" } else { "no" }`, "yes"},
+		{"elsif", `if false { 1 } elsif true { 2 } else { 3 }`, "2"},
+		{"if without else", `if false { 1 }`, "nil"},
+		{"call", `(-> (a, b) { a + b })(1, 2)`, "3"},
+		{"builtin", `len("abc")`, "3"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			v, _, err := run(t, d.src)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if v.String() != d.want {
+				t.Errorf("want <%s> got <%s>", d.want, v)
+			}
+		})
+	}
+}
+
+func TestEvalStatements(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"def", "def x = 1\nx", "1"},
+		{"def without init", "def x\nx", "nil"},
+		{"let", "def x = 1\nx = 2\nx", "2"},
+		{"self let", "def x = 10\nx += 1\nx -= 2\nx *= 3\nx /= 9\nx %= 2\nx", "1"},
+		{"key assign", "def a = [1, 2]\na[0] = 3\na[1] += 1\na", "[3, 3]"},
+		{"hash assign", "def h = {}\nh[\"k\"] = 1\nh", `{"k": 1}`},
+		{"while", "def i = 0\ndef s = 0\nwhile i < 5 {\n  i += 1\n  s += i\n}\ns", "15"},
+		{"break", "def i = 0\nwhile true {\n  if i == 3 { break }\n  i += 1\n}\ni", "3"},
+		{"continue", "def i = 0\ndef s = 0\nwhile i < 5 {\n  i += 1\n  if i % 2 == 0 { continue }\n  s += i\n}\ns", "9"},
+		{"return", "def f = -> (x) {\n  if x > 0 { return \"pos\" }\n  \"neg\"\n}\nf(1) + f(-1)", "posneg"},
+		{"closure", "def counter = -> {\n  def n = 0\n  -> {\n    n += 1\n  }\n}\ndef c = counter()\nc()\nc()\nc()", "3"},
+		{"recursion", "def fib = -> (n) {\n  if n < 2 { return n }\n  fib(n - 1) + fib(n - 2)\n}\nfib(10)", "55"},
+		{"block scope", "def x = 1\nif true {\n  def x = 2\n}\nx", "1"},
+		{"return in loop", "def f = -> {\n  while true {\n    return 1\n  }\n}\nf()", "1"},
+		{"interpolation", "def name = \"bob\"\ndef n = 2\n\"hello #{name}, #{n + 1} #{[nil, \"s\"]}\"", `hello bob, 3 [nil, "s"]`},
+		{"nested interpolation", "def x = 1\n\"a#{\"b#{x}\"}c\\#{x}\"", "ab1c#{x}"},
+		{"for array", "def s = 0\nfor x in [1, 2, 3] {\n  s += x\n}\ns", "6"},
+		{"for index", "def s = []\nfor i, x in [\"a\", \"b\"] {\n  push(s, [i, x])\n}\ns", `[[0, "a"], [1, "b"]]`},
+		{"for hash keys", "def s = []\nfor k in {\"a\": 1, \"b\": 2} {\n  push(s, k)\n}\ns", `["a", "b"]`},
+		{"for hash pairs", "def s = 0\nfor k, v in {\"a\": 1, \"b\": 2} {\n  s += v\n}\ns", "3"},
+		{"for string", "def s = []\nfor c in \"héy\" {\n  push(s, c)\n}\ns", `["h", "é", "y"]`},
+		{"for range", "def s = []\nfor i in range(3) {\n  push(s, i)\n}\nfor i in range(1, 10, 3) {\n  push(s, i)\n}\ns", "[0, 1, 2, 1, 4, 7]"},
+		{"for break and continue", "def s = 0\nfor i in range(10) {\n  if i == 5 { break }\n  if i % 2 == 0 { continue }\n  s += i\n}\ns", "4"},
+		{"for closures", "def fs = []\nfor i in range(3) {\n  push(fs, -> { i })\n}\n[fs[0](), fs[2]()]", "[0, 2]"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			v, _, err := run(t, d.src)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if v.String() != d.want {
+				t.Errorf("want <%s> got <%s>", d.want, v)
+			}
+		})
+	}
+}
+
+func TestEvalPuts(t *testing.T) {
+	_, out, err := run(t, "puts(\"Hello.\", 1)\nputs([\"a\"])")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	want := "Hello.\n1\n[\"a\"]\n"
+	if out != want {
+		t.Errorf("want <%#v> got <%#v>", want, out)
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"undefined", `x`, "undefined variable - x"},
+		{"undefined let", `x = 1`, "undefined variable - x"},
+		{"division by zero", `1 / 0`, "division by zero"},
+		{"type mismatch", `1 + "a"`, "undefined operator Add for int and string"},
+		{"not callable", `1()`, "not callable - int"},
+		{"arity", `(-> (a) { a })()`, "wrong number of arguments"},
+		{"break outside loop", `break`, "break outside of loop"},
+		{"break in function", "while true {\n  (-> { break })()\n}", "break outside of loop"},
+		{"unhashable", `{[1]: 2}`, "unhashable key - array"},
+		{"not iterable", `for x in 1 { x }`, "not iterable - int"},
+		{"range step", `range(1, 2, 0)`, "range step must not be zero"},
+		{"endless recursion", "def f = -> (n) { f(n + 1) }\nf(0)", "stack overflow"},
+		{"recursion too deep", "def f = -> (n) { if n < 1023 { f(n + 1) } else { n } }\nf(0)", "stack overflow"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			_, _, err := run(t, d.src)
+			if err == nil {
+				t.Errorf("want error <%s> got nil", d.want)
+				return
+			}
+			if !strings.Contains(err.Error(), d.want) {
+				t.Errorf("want <%s> got <%s>", d.want, err)
+			}
+		})
+	}
+}
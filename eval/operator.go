@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/object"
+)
+
+func evalPrefixExpression(in *Interpreter, x *ast.PrefixExpression, env *object.Environment) (object.Object, error) {
+	right, err := evalExpression(in, x.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := object.Prefix(x.Operator, right)
+	if err != nil {
+		return nil, in.errorf(x.Loc, "%v", err)
+	}
+	return v, nil
+}
+
+func evalInfixExpression(in *Interpreter, x *ast.InfixExpression, env *object.Environment) (object.Object, error) {
+	left, err := evalExpression(in, x.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := evalExpression(in, x.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := object.Infix(x.Operator, left, right)
+	if err != nil {
+		return nil, in.errorf(x.Loc, "%v", err)
+	}
+	return v, nil
+}
+
+// evalLogicalExpression evaluates the right operand only if the left one
+// does not decide the value.
+func evalLogicalExpression(in *Interpreter, x *ast.LogicalExpression, env *object.Environment) (object.Object, error) {
+	left, err := evalExpression(in, x.Left, env)
+	if err != nil {
+		return nil, err
+	}
+	if object.Truthy(left) == (x.Operator == ast.Or) {
+		return left, nil
+	}
+	return evalExpression(in, x.Right, env)
+}
+
+func evalKeyAccess(in *Interpreter, x *ast.KeyAccess, env *object.Environment) (object.Object, error) {
+	c, err := evalExpression(in, x.Container, env)
+	if err != nil {
+		return nil, err
+	}
+	k, err := evalExpression(in, x.Key, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := object.Index(c, k)
+	if err != nil {
+		return nil, in.errorf(x.Loc, "%v", err)
+	}
+	return v, nil
+}
+
+func evalKeyAssign(in *Interpreter, x *ast.KeyAssign, env *object.Environment) (object.Object, error) {
+	c, err := evalExpression(in, x.Left.Container, env)
+	if err != nil {
+		return nil, err
+	}
+	k, err := evalExpression(in, x.Left.Key, env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := evalExpression(in, x.Right, env)
+	if err != nil {
+		return nil, err
+	}
+	if err := object.SetIndex(c, k, v); err != nil {
+		return nil, in.errorf(x.Loc, "%v", err)
+	}
+	return v, nil
+}
@@ -0,0 +1,539 @@
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/lexer"
+	"github.com/arikui1911/goore/parser"
+)
+
+// Source parses src and returns it in canonical goore formatting,
+// comments included.
+func Source(src []byte, fileName string) ([]byte, error) {
+	l := lexer.NewWithMode(bytes.NewReader(src), lexer.ScanComments)
+	tree, err := parser.New(l, fileName).Parse()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := Fprint(&buf, tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Fprint writes prog to w in canonical goore formatting. Programs with
+// syntax errors are rejected.
+func Fprint(w io.Writer, prog *ast.Program) error {
+	if prog.Err != nil {
+		return prog.Err
+	}
+	p := &printer{comments: prog.Comments}
+	if len(prog.Statements) == 0 {
+		p.commentLines(p.comments.Get(prog).Inner)
+	} else if err := p.statements(prog.Statements); err != nil {
+		return err
+	}
+	if p.buf.Len() > 0 {
+		p.buf.WriteString("\n")
+	}
+	_, err := io.WriteString(w, p.buf.String())
+	return err
+}
+
+type printer struct {
+	buf      strings.Builder
+	indent   int
+	comments ast.CommentMap
+}
+
+func (p *printer) print(args ...any) {
+	for _, a := range args {
+		fmt.Fprint(&p.buf, a)
+	}
+}
+
+func (p *printer) newline() {
+	p.buf.WriteString("\n")
+	for i := 0; i < p.indent; i++ {
+		p.buf.WriteString("\t")
+	}
+}
+
+func (p *printer) statements(stmts []ast.Statement) error {
+	return entries(p, stmts, false, "", func(s ast.Statement) error {
+		return p.statement(s)
+	})
+}
+
+// entries prints nodes one per line together with their comments, keeping
+// a single blank line where the source separated them by one or more. With
+// breakFirst the first line is started too; after is printed right behind
+// each node, before a trailing comment on the same line.
+func entries[T ast.Node](p *printer, nodes []T, breakFirst bool, after string, print func(T) error) error {
+	first := !breakFirst
+	last := -1
+	line := func(l int) {
+		if !first {
+			if last >= 0 && l > last+1 {
+				p.buf.WriteString("\n")
+			}
+			p.newline()
+		}
+		first = false
+	}
+	comment := func(c *ast.Comment) {
+		line(c.Loc.StartLine)
+		p.print(c.Text)
+		last = c.Loc.EndLine
+	}
+
+	for _, n := range nodes {
+		cs := p.comments.Get(n)
+		for _, c := range cs.Leading {
+			comment(c)
+		}
+		line(n.Location().StartLine)
+		if err := print(n); err != nil {
+			return err
+		}
+		p.print(after)
+		last = n.Location().EndLine
+		for i, c := range cs.Trailing {
+			if i == 0 && c.Loc.StartLine == last {
+				p.print(" ", c.Text)
+				continue
+			}
+			comment(c)
+		}
+	}
+	return nil
+}
+
+// commentLines prints comments that belong to no statement, one per line.
+func (p *printer) commentLines(cs []*ast.Comment) {
+	last := -1
+	for i, c := range cs {
+		if i > 0 {
+			if c.Loc.StartLine > last+1 {
+				p.buf.WriteString("\n")
+			}
+			p.newline()
+		}
+		p.print(c.Text)
+		last = c.Loc.EndLine
+	}
+}
+
+func (p *printer) block(owner ast.Node, stmts []ast.Statement) error {
+	inner := p.comments.Get(owner).Inner
+	if len(stmts) == 0 && len(inner) == 0 {
+		p.print("{}")
+		return nil
+	}
+	p.print("{")
+	p.indent++
+	p.newline()
+	if len(stmts) == 0 {
+		p.commentLines(inner)
+	} else if err := p.statements(stmts); err != nil {
+		return err
+	}
+	p.indent--
+	p.newline()
+	p.print("}")
+	return nil
+}
+
+func (p *printer) statement(s ast.Statement) error {
+	switch s := s.(type) {
+	case *ast.ExpressionStatement:
+		return p.expression(s.Expression, lowestPrec)
+	case *ast.Def:
+		p.print("def ", s.Name.Name)
+		if s.Init == nil {
+			return nil
+		}
+		p.print(" = ")
+		return p.expression(s.Init, lowestPrec)
+	case *ast.While:
+		p.print("while ")
+		if err := p.expression(s.Cond, lowestPrec); err != nil {
+			return err
+		}
+		p.print(" ")
+		return p.block(s, s.Body)
+	case *ast.For:
+		p.print("for ")
+		for i, v := range s.Vars {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.print(v.Name)
+		}
+		p.print(" in ")
+		if err := p.expression(s.Iterable, lowestPrec); err != nil {
+			return err
+		}
+		p.print(" ")
+		return p.block(s, s.Body)
+	case *ast.Break:
+		p.print("break")
+	case *ast.Continue:
+		p.print("continue")
+	case *ast.Return:
+		p.print("return")
+		if s.Expression == nil {
+			return nil
+		}
+		p.print(" ")
+		return p.expression(s.Expression, lowestPrec)
+	default:
+		return fmt.Errorf("%s: cannot format %T", s.Location(), s)
+	}
+	return nil
+}
+
+// Operator binding strength, mirroring the parser's precedence table.
+const (
+	lowestPrec = iota
+	letPrec
+	orPrec
+	andPrec
+	equalityPrec
+	comparePrec
+	additivePrec
+	multivePrec
+	prefixPrec
+	postfixPrec
+	primaryPrec
+)
+
+var infixPrecs = map[ast.Operation]int{
+	ast.Or:  orPrec,
+	ast.And: andPrec,
+	ast.Eq:  equalityPrec,
+	ast.Ne:  equalityPrec,
+	ast.Le:  comparePrec,
+	ast.Ge:  comparePrec,
+	ast.Lt:  comparePrec,
+	ast.Gt:  comparePrec,
+	ast.Add: additivePrec,
+	ast.Sub: additivePrec,
+	ast.Mul: multivePrec,
+	ast.Div: multivePrec,
+	ast.Mod: multivePrec,
+}
+
+var operatorTexts = map[ast.Operation]string{
+	ast.Plus:  "+",
+	ast.Minus: "-",
+	ast.Not:   "!",
+	ast.Eq:    "==",
+	ast.Ne:    "!=",
+	ast.Le:    "<=",
+	ast.Ge:    ">=",
+	ast.Lt:    "<",
+	ast.Gt:    ">",
+	ast.Add:   "+",
+	ast.Sub:   "-",
+	ast.Mul:   "*",
+	ast.Div:   "/",
+	ast.Mod:   "%",
+	ast.And:   "&&",
+	ast.Or:    "||",
+}
+
+func precedenceOf(x ast.Expression) int {
+	switch x := x.(type) {
+	case *ast.Let, *ast.KeyAssign:
+		return letPrec
+	case *ast.InfixExpression:
+		return infixPrecs[x.Operator]
+	case *ast.LogicalExpression:
+		return infixPrecs[x.Operator]
+	case *ast.PrefixExpression:
+		return prefixPrec
+	case *ast.Call, *ast.KeyAccess:
+		return postfixPrec
+	}
+	return primaryPrec
+}
+
+// expression prints x, parenthesized when it binds more loosely than prec.
+func (p *printer) expression(x ast.Expression, prec int) error {
+	if precedenceOf(x) < prec {
+		p.print("(")
+		defer p.print(")")
+	}
+
+	switch x := x.(type) {
+	case *ast.Identifier:
+		p.print(x.Name)
+	case *ast.NilLiteral:
+		p.print("nil")
+	case *ast.BoolLiteral:
+		p.print(strconv.FormatBool(x.Value))
+	case *ast.IntLiteral:
+		p.print(formatInt(x))
+	case *ast.FloatLiteral:
+		p.print(formatFloat(x))
+	case *ast.StringLiteral:
+		p.print(quote(x.Value))
+	case *ast.InterpolatedString:
+		p.print(`"`)
+		for _, part := range x.Parts {
+			if s, ok := part.(*ast.StringLiteral); ok {
+				p.print(escape(s.Value))
+				continue
+			}
+			p.print("#{")
+			if err := p.expression(part, lowestPrec); err != nil {
+				return err
+			}
+			p.print("}")
+		}
+		p.print(`"`)
+	case *ast.ArrayLiteral:
+		return list(p, "[", "]", x.Loc.StartLine, x, x.Elements, func(e ast.Expression) error {
+			return p.expression(e, lowestPrec)
+		})
+	case *ast.HashLiteral:
+		return list(p, "{", "}", x.Loc.StartLine, x, x.Pairs, func(e *ast.HashEntry) error {
+			if err := p.expression(e.Key, lowestPrec); err != nil {
+				return err
+			}
+			p.print(":")
+			return p.operand(e, " ", e.Value, lowestPrec)
+		})
+	case *ast.FunctionLiteral:
+		p.print("-> ")
+		if len(x.Parameters) > 0 {
+			err := list(p, "(", ")", x.Loc.StartLine, nil, x.Parameters, func(param *ast.Identifier) error {
+				p.print(param.Name)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			p.print(" ")
+		}
+		return p.block(x, x.Statements)
+	case *ast.PrefixExpression:
+		p.print(operatorTexts[x.Operator])
+		return p.operand(x, "", x.Right, prefixPrec)
+	case *ast.InfixExpression:
+		prec := infixPrecs[x.Operator]
+		if err := p.expression(x.Left, prec); err != nil {
+			return err
+		}
+		p.print(" ", operatorTexts[x.Operator])
+		return p.operand(x, " ", x.Right, prec+1)
+	case *ast.LogicalExpression:
+		prec := infixPrecs[x.Operator]
+		if err := p.expression(x.Left, prec); err != nil {
+			return err
+		}
+		p.print(" ", operatorTexts[x.Operator])
+		return p.operand(x, " ", x.Right, prec+1)
+	case *ast.If:
+		return p.ifExpression(x)
+	case *ast.Call:
+		if err := p.expression(x.Function, postfixPrec); err != nil {
+			return err
+		}
+		return list(p, "(", ")", x.Function.Location().EndLine, x, x.Arguments, func(e ast.Expression) error {
+			return p.expression(e, lowestPrec)
+		})
+	case *ast.KeyAccess:
+		if err := p.expression(x.Container, postfixPrec); err != nil {
+			return err
+		}
+		p.print("[")
+		if err := p.operand(x, "", x.Key, lowestPrec); err != nil {
+			return err
+		}
+		p.print("]")
+	case *ast.Let:
+		p.print(x.Left.Name)
+		return p.assignment(x, x.Left, x.Right)
+	case *ast.KeyAssign:
+		if err := p.expression(x.Left, postfixPrec); err != nil {
+			return err
+		}
+		return p.assignment(x, x.Left, x.Right)
+	default:
+		return fmt.Errorf("%s: cannot format %T", x.Location(), x)
+	}
+	return nil
+}
+
+// assignment prints the operator and right side of x, a Let or KeyAssign.
+// The parser desugars `x += y` into `x = x + y` sharing the very same left
+// node, which is how the compound form is recognized again.
+func (p *printer) assignment(x ast.Expression, left ast.Expression, right ast.Expression) error {
+	if inf, ok := right.(*ast.InfixExpression); ok && inf.Left == left {
+		switch inf.Operator {
+		case ast.Add, ast.Sub, ast.Mul, ast.Div, ast.Mod:
+			p.print(" ", operatorTexts[inf.Operator], "=")
+			return p.operand(x, " ", inf.Right, lowestPrec)
+		}
+	}
+	p.print(" =")
+	return p.operand(x, " ", right, lowestPrec)
+}
+
+// operand prints the last operand of x behind its operator, separated by
+// sep. Comments written right behind the operator are kept there, and the
+// operand then starts a line of its own, indented once more.
+func (p *printer) operand(x ast.Node, sep string, operand ast.Expression, prec int) error {
+	inner := p.comments.Get(x).Inner
+	if len(inner) == 0 {
+		p.print(sep)
+		return p.expression(operand, prec)
+	}
+	p.indent++
+	defer func() { p.indent-- }()
+	for i, c := range inner {
+		if i > 0 {
+			p.newline()
+		} else {
+			p.print(" ")
+		}
+		p.print(c.Text)
+	}
+	p.newline()
+	return p.expression(operand, prec)
+}
+
+func (p *printer) ifExpression(x *ast.If) error {
+	p.print("if ")
+	for {
+		if err := p.expression(x.Test, lowestPrec); err != nil {
+			return err
+		}
+		p.print(" ")
+		if err := p.block(x, x.Body); err != nil {
+			return err
+		}
+		switch alt := x.Alt.(type) {
+		case nil:
+			return nil
+		case *ast.If:
+			p.print(" elsif ")
+			x = alt
+		case *ast.Else:
+			p.print(" else ")
+			return p.block(alt, alt.Body)
+		default:
+			return fmt.Errorf("%s: cannot format %T", alt.Location(), alt)
+		}
+	}
+}
+
+// list prints a comma separated list. When the source placed the first
+// element on a later line than the opening delimiter, or comments have to be
+// kept, every element goes on its own line followed by a trailing comma.
+// Comments inside the delimiters that belong to no element are owner's.
+func list[T ast.Node](p *printer, open string, close string, openLine int, owner ast.Node, elems []T, elem func(T) error) error {
+	var inner []*ast.Comment
+	if owner != nil {
+		inner = p.comments.Get(owner).Inner
+	}
+	multiLine := len(inner) > 0 || len(elems) > 0 && elems[0].Location().StartLine > openLine
+	for _, e := range elems {
+		if cs := p.comments.Get(e); len(cs.Leading) > 0 || len(cs.Trailing) > 0 {
+			multiLine = true
+		}
+	}
+
+	p.print(open)
+	if !multiLine {
+		for i, e := range elems {
+			if i > 0 {
+				p.print(", ")
+			}
+			if err := elem(e); err != nil {
+				return err
+			}
+		}
+		p.print(close)
+		return nil
+	}
+
+	p.indent++
+	if err := entries(p, elems, true, ",", elem); err != nil {
+		return err
+	}
+	if len(inner) > 0 {
+		p.newline()
+		p.commentLines(inner)
+	}
+	p.indent--
+	p.newline()
+	p.print(close)
+	return nil
+}
+
+// formatInt keeps the base and digit separators the literal is written
+// with.
+func formatInt(x *ast.IntLiteral) string {
+	if x.Raw != "" {
+		return x.Raw
+	}
+	return strconv.Itoa(x.Value)
+}
+
+// formatFloat normalizes a plain decimal literal but keeps one written
+// with an exponent or digit separators.
+func formatFloat(x *ast.FloatLiteral) string {
+	if strings.ContainsAny(x.Raw, "eE_") {
+		return x.Raw
+	}
+	s := strconv.FormatFloat(x.Value, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+func quote(s string) string {
+	return `"` + escape(s) + `"`
+}
+
+// escape returns s as it is written between the quotes of a string.
+func escape(s string) string {
+	var b strings.Builder
+	for i, c := range s {
+		switch c {
+		case '"', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case 0:
+			b.WriteString(`\0`)
+		case '#':
+			// not to begin an interpolation
+			if strings.HasPrefix(s[i+1:], "{") {
+				b.WriteRune('\\')
+			}
+			b.WriteRune(c)
+		default:
+			if c != '\t' && unicode.IsControl(c) {
+				fmt.Fprintf(&b, `\u{%X}`, c)
+				continue
+			}
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
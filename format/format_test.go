@@ -0,0 +1,120 @@
+package format_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/format"
+	"github.com/arikui1911/goore/parser"
+)
+
+func TestSource(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"empty", ``, ""},
+		{"spacing", `x=1+2*3`, "x = 1 + 2 * 3\n"},
+		{"def", "def  x\ndef y=1", "def x\ndef y = 1\n"},
+		{"semicolons", `a; b;c`, "a\nb\nc\n"},
+		{"blank lines", "a\n\n\n\nb\nc", "a\n\nb\nc\n"},
+		{"parens kept", `(1 + 2) * 3`, "(1 + 2) * 3\n"},
+		{"parens dropped", `((1 * 2)) + (3)`, "1 * 2 + 3\n"},
+		{"right associativity", `1 - (2 - 3)`, "1 - (2 - 3)\n"},
+		{"prefix", `-(1 + 2); !!x; - -1`, "-(1 + 2)\n!!x\n--1\n"},
+		{"compound let", `x+=1; x = x + 1; a[0] *= 2`, "x += 1\nx = x + 1\na[0] *= 2\n"},
+		{"chained let", `a = b = 1`, "a = b = 1\n"},
+		{"nested let", `1 + (x = 2)`, "1 + (x = 2)\n"},
+		{"logical", `a&&b||!c&&(d||e)`, "a && b || !c && (d || e)\n"},
+		{"logical parens kept", `(a || b) && c == (d && e)`, "(a || b) && c == (d && e)\n"},
+		{"strings", `"a\"b\\c\nd	e"`, "\"a\\\"b\\\\c\\nd\te\"\n"},
+		{"interpolation", `"a#{ x+1 }\#{b} #c #{"d#{e}"}"`, "\"a#{x + 1}\\#{b} #c #{\"d#{e}\"}\"\n"},
+		{"escapes", "\"\\r\\0\\x07\\u{e9}\"\n`raw\\`\n\"\"\"\n  a\n  \"\"\"", "\"\\r\\0\\u{7}é\"\n\"raw\\\\\"\n\"a\"\n"},
+		{"floats", `[1.0, 2.50, 0.125]`, "[1.0, 2.5, 0.125]\n"},
+		{"number forms", `[0xFF, 0b1_0, 1_000, 1.50e3, 2.5E-3]`, "[0xFF, 0b1_0, 1_000, 1.50e3, 2.5E-3]\n"},
+		{"while", "while x { x -= 1; break }", "while x {\n\tx -= 1\n\tbreak\n}\n"},
+		{"empty block", "while x {\n}", "while x {}\n"},
+		{"for", "for  k,v in  h {\nputs(k)\n}", "for k, v in h {\n\tputs(k)\n}\n"},
+		{"if chain", "if a { 1 } elsif b { 2 } else { 3 }", "if a {\n\t1\n} elsif b {\n\t2\n} else {\n\t3\n}\n"},
+		{"function", "def f = -> (a,b) { return a+b }", "def f = -> (a, b) {\n\treturn a + b\n}\n"},
+		{"function without parameters", "-> { 1 }()", "-> {\n\t1\n}()\n"},
+		{"single line array", "[1,2,\n3]", "[1, 2, 3]\n"},
+		{"multi line array", "[\n1,2,\n3]", "[\n\t1,\n\t2,\n\t3,\n]\n"},
+		{"multi line hash", "{\n\"a\": 1, \"b\": [2]}", "{\n\t\"a\": 1,\n\t\"b\": [2],\n}\n"},
+		{"multi line call", "f(\n1, g(2))", "f(\n\t1,\n\tg(2),\n)\n"},
+		{"nested indent", "def f = -> {\nwhile true {\nputs([\n1])\n}\n}", "def f = -> {\n\twhile true {\n\t\tputs([\n\t\t\t1,\n\t\t])\n\t}\n}\n"},
+		{"return", "def f = -> {\nreturn\n}", "def f = -> {\n\treturn\n}\n"},
+		{"key access", `a[1][2+3]`, "a[1][2 + 3]\n"},
+		{"only comments", "# a\n\n\n# b", "# a\n\n# b\n"},
+		{"comments around statements", "# lead\nx=1 # trail\n\n# next\ny", "# lead\nx = 1 # trail\n\n# next\ny\n"},
+		{"comments in block", "while x {\n# lead\nx-=1 # dec\n# last\n}", "while x {\n\t# lead\n\tx -= 1 # dec\n\t# last\n}\n"},
+		{"comment in empty block", "while x { # nothing\n}", "while x {\n\t# nothing\n}\n"},
+		{"comments in array", "[1, # one\n2]", "[\n\t1, # one\n\t2,\n]\n"},
+		{"comment in empty call", "f( # none\n)", "f(\n\t# none\n)\n"},
+		{"comment in parameters", "-> (a, # first\nb) {}", "-> (\n\ta, # first\n\tb,\n) {}\n"},
+		{"comment behind infix operator", "x = 1 + # mid\n  2", "x = 1 + # mid\n\t2\n"},
+		{"comments behind logical operator", "a && # one\n# two\nb", "a && # one\n\t# two\n\tb\n"},
+		{"comment behind hash colon", "z = {\"a\": # k\n 1}", "z = {\"a\": # k\n\t1}\n"},
+		{"comment behind prefix operator", "puts(- # neg\n 1)", "puts(- # neg\n\t1)\n"},
+		{"comment behind last argument", "foo(1, 2 # c2\n)", "foo(\n\t1,\n\t2, # c2\n)\n"},
+		{"comment behind let", "x += # inc\n1", "x += # inc\n\t1\n"},
+		{"comment behind bracket", "a[ # key\n0] = 1", "a[ # key\n\t0] = 1\n"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			got, err := format.Source([]byte(d.src), "test.goore")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if string(got) != d.want {
+				t.Errorf("want <%#v> got <%#v>", d.want, string(got))
+				return
+			}
+			testIdempotent(t, got)
+			testRoundTrip(t, d.src, string(got))
+		})
+	}
+}
+
+func TestSourceSyntaxError(t *testing.T) {
+	_, err := format.Source([]byte("def 1"), "test.goore")
+	if err == nil {
+		t.Error("want error got nil")
+	}
+}
+
+func testIdempotent(t *testing.T, src []byte) {
+	again, err := format.Source(src, "test.goore")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !bytes.Equal(again, src) {
+		t.Errorf("not idempotent: <%#v> then <%#v>", string(src), string(again))
+	}
+}
+
+var locationPattern = regexp.MustCompile(`\(\d+:\d+\):\(\d+:\d+\):`)
+
+func testRoundTrip(t *testing.T, src string, formatted string) {
+	want := dumpWithoutLocations(t, src)
+	got := dumpWithoutLocations(t, formatted)
+	if got != want {
+		t.Errorf("tree changed:\n%s\nto:\n%s", want, got)
+	}
+}
+
+func dumpWithoutLocations(t *testing.T, src string) string {
+	tree, err := parser.ParseString(src, "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	ast.Dump(tree, &buf)
+	return locationPattern.ReplaceAllString(buf.String(), "")
+}
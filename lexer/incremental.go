@@ -0,0 +1,268 @@
+package lexer
+
+import (
+	"errors"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/token"
+)
+
+// State is what lexing carries over to the start of a line: the line, its
+// byte offset and the tag of the last token, which decides whether the
+// next line break yields a Newline.
+type State struct {
+	Line    int            `json:"line"`
+	Offset  int            `json:"offset"`
+	LastTag token.TokenTag `json:"lastTag"`
+}
+
+// NewAt returns a lexer resuming at the start of a line from a state
+// recorded there. src holds the source from that line on; the locations
+// of its tokens count from st.Line and st.Offset.
+func NewAt(src io.Reader, mode Mode, st State) *Lexer {
+	l := NewWithMode(src, mode)
+	l.line = st.Line
+	l.offset = st.Offset
+	l.lastTag = st.LastTag
+	if st.Line > 1 {
+		l.srcLastRune = '\n'
+	}
+	return l
+}
+
+// State returns the state of l if it is at the start of a line, as after
+// returning the Newline token of a line break.
+func (l *Lexer) State() (State, bool) {
+	if l.col != 1 || l.hasSavedRune || len(l.interps) > 0 || l.held != nil {
+		return State{}, false
+	}
+	return State{Line: l.line, Offset: l.offset, LastTag: l.lastTag}, true
+}
+
+// Tokens holds the tokens of a source together with the lexer state at
+// the start of its lines, so that an edit only needs the lines around it
+// lexed again.
+type Tokens struct {
+	mode       Mode
+	src        string
+	lineStarts []int
+	states     []State
+	tokens     []token.Token
+	diags      diag.List
+}
+
+// A Change tells which tokens an Update replaced: Tokens()[Start:NewEnd]
+// took the place of the old tokens at [Start:OldEnd].
+type Change struct {
+	Start  int
+	OldEnd int
+	NewEnd int
+}
+
+// Lex lexes all of src.
+func Lex(src string, mode Mode) *Tokens {
+	ts := &Tokens{mode: mode}
+	ts.setSource(src)
+	initial := State{Line: 1}
+	toks, diags, states := ts.lexUntil(initial, func(State) bool { return false })
+	ts.tokens, ts.diags, ts.states = toks, diags, append([]State{initial}, states...)
+	return ts
+}
+
+// Source returns the current source.
+func (ts *Tokens) Source() string {
+	return ts.src
+}
+
+// Tokens returns the tokens up to and including EOF. Tokens the lexer
+// failed on are left out and reported by Err.
+func (ts *Tokens) Tokens() []token.Token {
+	return ts.tokens
+}
+
+// Err returns the lexical errors as a diag.List, or nil.
+func (ts *Tokens) Err() error {
+	return ts.diags.Err()
+}
+
+// StateAt returns the state recorded at the start of line, if lexing
+// passed it outside any token.
+func (ts *Tokens) StateAt(line int) (State, bool) {
+	i := sort.Search(len(ts.states), func(i int) bool { return ts.states[i].Line >= line })
+	if i < len(ts.states) && ts.states[i].Line == line {
+		return ts.states[i], true
+	}
+	return State{}, false
+}
+
+// Update replaces the lines from startLine up to but excluding endLine
+// with text and lexes again from the last state recorded at or before
+// startLine, until it reaches a line behind the edit with the state it
+// had before. The tokens behind that line are kept with their locations
+// moved. If text does not end with a line break, it is joined with line
+// endLine. startLine is at most the last line, which is the empty one
+// behind the final line break if any; text inserted there is appended.
+func (ts *Tokens) Update(startLine, endLine int, text string) (Change, error) {
+	if startLine < 1 || startLine > len(ts.lineStarts) || endLine < startLine || endLine > len(ts.lineStarts)+1 {
+		return Change{}, errors.New("lexer: line range out of source")
+	}
+	end := len(ts.src)
+	if endLine <= len(ts.lineStarts) {
+		end = ts.lineStarts[endLine-1]
+	}
+	oldLines := len(ts.lineStarts)
+	oldLen := len(ts.src)
+	ts.setSource(ts.src[:ts.lineStarts[startLine-1]] + text + ts.src[end:])
+
+	// the old line sameLine and all behind it are untouched, moved by delta
+	// lines and offDelta bytes
+	delta := startLine + strings.Count(text, "\n") - endLine
+	offDelta := len(ts.src) - oldLen
+	sameLine := endLine
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		sameLine++
+	}
+
+	restart := ts.states[sort.Search(len(ts.states), func(i int) bool { return ts.states[i].Line > startLine })-1]
+	start := sort.Search(len(ts.tokens), func(i int) bool { return ts.tokens[i].Location.StartLine >= restart.Line })
+
+	var resumed State
+	converged := false
+	toks, diags, states := ts.lexUntil(restart, func(st State) bool {
+		old := st.Line - delta
+		if old < sameLine || old > oldLines {
+			return false
+		}
+		if prev, ok := ts.StateAt(old); ok && prev.LastTag == st.LastTag {
+			resumed, converged = st, true
+			return true
+		}
+		return false
+	})
+
+	oldEnd := len(ts.tokens)
+	var tailStates []State
+	var tailDiags diag.List
+	if converged {
+		old := resumed.Line - delta
+		oldEnd = sort.Search(len(ts.tokens), func(i int) bool { return ts.tokens[i].Location.StartLine >= old })
+		for _, st := range ts.states {
+			if st.Line >= old {
+				st.Line += delta
+				st.Offset += offDelta
+				tailStates = append(tailStates, st)
+			}
+		}
+		for _, d := range ts.diags {
+			if d.Loc.StartLine >= old {
+				c := *d
+				c.Loc = moveLocation(c.Loc, delta, offDelta)
+				tailDiags = append(tailDiags, &c)
+			}
+		}
+	}
+	tail := make([]token.Token, len(ts.tokens)-oldEnd)
+	for i, t := range ts.tokens[oldEnd:] {
+		t.Location = moveLocation(t.Location, delta, offDelta)
+		tail[i] = t
+	}
+
+	var keptDiags diag.List
+	for _, d := range ts.diags {
+		if d.Loc.StartLine < restart.Line {
+			keptDiags = append(keptDiags, d)
+		}
+	}
+	keptStates := ts.states[:sort.Search(len(ts.states), func(i int) bool { return ts.states[i].Line > restart.Line })]
+
+	c := Change{Start: start, OldEnd: oldEnd, NewEnd: start + len(toks)}
+	ts.tokens = append(append(ts.tokens[:start:start], toks...), tail...)
+	ts.diags = append(append(keptDiags, diags...), tailDiags...)
+	ts.states = append(append(keptStates[:len(keptStates):len(keptStates)], states...), tailStates...)
+	return c, nil
+}
+
+// Lexer returns a lexer that reads the tokens of ts again rather than
+// the source. Each error comes right before the first token that ends
+// behind the error's start, as it does when the source is lexed.
+func (ts *Tokens) Lexer() *Lexer {
+	return &Lexer{replay: ts.tokens, replayDiags: ts.diags}
+}
+
+func (l *Lexer) nextReplayed() (token.Token, error) {
+	t := l.replay[0]
+	if len(l.replayDiags) > 0 && (t.Tag == token.EOF || l.replayDiags[0].Loc.StartOffset < t.Location.EndOffset) {
+		d := l.replayDiags[0]
+		l.replayDiags = l.replayDiags[1:]
+		return token.Token{}, d
+	}
+	// EOF repeats
+	if len(l.replay) > 1 {
+		l.replay = l.replay[1:]
+	}
+	return t, nil
+}
+
+func (ts *Tokens) setSource(src string) {
+	ts.src = src
+	ts.lineStarts = []int{0}
+	for i, c := range src {
+		if c == '\n' {
+			ts.lineStarts = append(ts.lineStarts, i+1)
+		}
+	}
+}
+
+// lexUntil lexes from line st.Line until EOF or a line start that stop
+// accepts, st's included. It returns the tokens and errors before that
+// line and the states of the lines in between.
+func (ts *Tokens) lexUntil(st State, stop func(State) bool) ([]token.Token, diag.List, []State) {
+	if stop(st) {
+		return nil, nil, nil
+	}
+	l := NewAt(strings.NewReader(ts.src[ts.lineStarts[st.Line-1]:]), ts.mode, st)
+	var states []State
+	stopLine := 0
+	l.onLine = func(s State) {
+		if stopLine > 0 || s.Line > len(ts.lineStarts) {
+			return
+		}
+		if stop(s) {
+			stopLine = s.Line
+			return
+		}
+		states = append(states, s)
+	}
+
+	var toks []token.Token
+	var diags diag.List
+	for stopLine == 0 {
+		t, err := l.NextToken()
+		if err != nil {
+			// reading a string fails with diagnostics only
+			var d *diag.Diagnostic
+			if errors.As(err, &d) && (stopLine == 0 || d.Loc.StartLine < stopLine) {
+				diags = append(diags, d)
+			}
+			continue
+		}
+		if stopLine == 0 || t.Location.StartLine < stopLine {
+			toks = append(toks, t)
+		}
+		if t.Tag == token.EOF {
+			break
+		}
+	}
+	return toks, diags, states
+}
+
+func moveLocation(loc token.Location, delta int, offDelta int) token.Location {
+	loc.StartLine += delta
+	loc.EndLine += delta
+	loc.StartOffset += offDelta
+	loc.EndOffset += offDelta
+	return loc
+}
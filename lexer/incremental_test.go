@@ -0,0 +1,197 @@
+package lexer_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/lexer"
+	"github.com/arikui1911/goore/token"
+)
+
+const incrementalSrc = `def f = -> (a) {
+  # twice
+  a * 2
+}
+x = "multi
+line"
+while x {
+  break
+}
+f(x)
+`
+
+func TestNewAt(t *testing.T) {
+	l := lexer.New(strings.NewReader("a\nb\n"))
+	for {
+		tok, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tok.Tag == token.Newline {
+			break
+		}
+	}
+	st, ok := l.State()
+	if !ok {
+		t.Fatal("want state at line start")
+	}
+	if want := (lexer.State{Line: 2, Offset: 2, LastTag: token.Newline}); st != want {
+		t.Errorf("want <%v> got <%v>", want, st)
+	}
+
+	r := lexer.NewAt(strings.NewReader("b + 1\n"), 0, lexer.State{Line: 7, Offset: 40, LastTag: token.Identifier})
+	want := []string{"Identifier(7:1):(7:1)", "Add(7:3):(7:3)", "IntLiteral(7:5):(7:5)", "Newline(7:6):(7:6)", "EOF(8:1):(8:1)"}
+	for _, w := range want {
+		tok, err := r.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprintf("%s%s", tok.Tag, tok.Location); got != w {
+			t.Errorf("want <%v> got <%v>", w, got)
+		}
+	}
+}
+
+func TestTokensUpdate(t *testing.T) {
+	table := []struct {
+		name      string
+		startLine int
+		endLine   int
+		text      string
+		relexed   int
+	}{
+		{"change a line", 3, 4, "  a * 3\n", 4},
+		{"insert lines", 10, 10, "g()\nh()\n", 8},
+		{"delete lines", 7, 10, "", 0},
+		{"join with next line", 10, 11, "f(x) + ", 6},
+		{"open a string", 3, 4, "  \"a\n", 5},
+		{"close the string", 5, 7, "x = \"single\"\n", 4},
+		{"append", 11, 11, "y\n", 2},
+		{"newline behind brace", 9, 10, "}\n\n\n", 2},
+		{"multiline string", 5, 7, "x = \"\"\"\n  multi\n  \"\"\"\n", 4},
+		{"interpolation over lines", 10, 11, "f(\"#{\nx\n}\")\n", 7},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			ts := lexer.Lex(incrementalSrc, lexer.ScanComments)
+			c, err := ts.Update(d.startLine, d.endLine, d.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			full := lexer.Lex(ts.Source(), lexer.ScanComments)
+			if !reflect.DeepEqual(ts.Tokens(), full.Tokens()) {
+				t.Errorf("want <%v> got <%v>", full.Tokens(), ts.Tokens())
+			}
+			if fmt.Sprint(ts.Err()) != fmt.Sprint(full.Err()) {
+				t.Errorf("want error <%v> got <%v>", full.Err(), ts.Err())
+			}
+			for line := 1; line <= strings.Count(ts.Source(), "\n")+1; line++ {
+				st, ok := ts.StateAt(line)
+				want, wantOK := full.StateAt(line)
+				if st != want || ok != wantOK {
+					t.Errorf("line %d: want state <%v %v> got <%v %v>", line, want, wantOK, st, ok)
+				}
+			}
+			if c.NewEnd-c.Start != d.relexed {
+				t.Errorf("want <%d> tokens lexed again got <%d> (%+v)", d.relexed, c.NewEnd-c.Start, c)
+			}
+		})
+	}
+}
+
+func TestTokensUpdateOutOfSource(t *testing.T) {
+	table := []struct {
+		src       string
+		startLine int
+		endLine   int
+	}{
+		{"a\n", 2, 4},
+		{"a\n", 0, 1},
+		{"a\n", 2, 1},
+		{"a = 1\nb = 2\n", 4, 4},
+		{"a = 1\nb = 2", 3, 3},
+		{"", 2, 2},
+	}
+
+	for _, d := range table {
+		t.Run(fmt.Sprintf("%q %d %d", d.src, d.startLine, d.endLine), func(t *testing.T) {
+			ts := lexer.Lex(d.src, 0)
+			if _, err := ts.Update(d.startLine, d.endLine, "x\n"); err == nil {
+				t.Error("want error got nil")
+			}
+		})
+	}
+}
+
+func TestTokensUpdateAfterLastLine(t *testing.T) {
+	table := []struct {
+		src       string
+		startLine int
+		endLine   int
+		want      string
+	}{
+		{"a = 1\nb = 2\n", 3, 3, "a = 1\nb = 2\nx\n"},
+		{"a = 1\nb = 2\n", 3, 4, "a = 1\nb = 2\nx\n"},
+		{"a = 1\nb = 2", 2, 3, "a = 1\nx\n"},
+		{"", 1, 1, "x\n"},
+	}
+
+	for _, d := range table {
+		t.Run(fmt.Sprintf("%q %d %d", d.src, d.startLine, d.endLine), func(t *testing.T) {
+			ts := lexer.Lex(d.src, 0)
+			if _, err := ts.Update(d.startLine, d.endLine, "x\n"); err != nil {
+				t.Fatal(err)
+			}
+			if ts.Source() != d.want {
+				t.Errorf("want <%#v> got <%#v>", d.want, ts.Source())
+			}
+			full := lexer.Lex(ts.Source(), 0)
+			if !reflect.DeepEqual(ts.Tokens(), full.Tokens()) {
+				t.Errorf("want <%v> got <%v>", full.Tokens(), ts.Tokens())
+			}
+		})
+	}
+}
+
+func TestTokensLexer(t *testing.T) {
+	table := []string{
+		incrementalSrc,
+		"x $ y\n",
+		"x = 1__0 + 0x\n",
+		"x = \"a\\qb\"\n",
+		"x = \"#{1}\\q#{2}\"\n",
+		"x = \"open\ny",
+		"",
+	}
+
+	drain := func(l *lexer.Lexer) []string {
+		var got []string
+		for {
+			tok, err := l.NextToken()
+			if err != nil {
+				got = append(got, err.Error())
+				continue
+			}
+			got = append(got, tok.String())
+			if tok.Tag == token.EOF {
+				return got
+			}
+		}
+	}
+	for _, src := range table {
+		t.Run(fmt.Sprintf("%q", src), func(t *testing.T) {
+			want := drain(lexer.New(strings.NewReader(src)))
+			l := lexer.Lex(src, 0).Lexer()
+			got := drain(l)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("want <%v> got <%v>", want, got)
+			}
+			if tok, err := l.NextToken(); err != nil || tok.Tag != token.EOF {
+				t.Errorf("want EOF again got <%v %v>", tok, err)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"io"
 	"strings"
 	"unicode"
+	"unicode/utf16"
 
+	"github.com/arikui1911/goore/diag"
 	"github.com/arikui1911/goore/token"
 )
 
@@ -14,16 +16,52 @@ type Lexer struct {
 	src          *bufio.Reader
 	line         int
 	col          int
+	offset       int
+	col16        int
 	lastNlCol    int
+	lastNlCol16  int
+	lastSize     int
 	srcLastRune  rune
 	savedRune    rune
+	savedSize    int
 	hasSavedRune bool
 	lastTag      token.TokenTag
+	mode         Mode
+	// interps holds for each interpolation being lexed the number of
+	// braces open in it, the innermost last.
+	interps []int
+	// pending holds diagnostics about the last token, which is held
+	// until they are returned.
+	pending []*diag.Diagnostic
+	held    *token.Token
+	// onLine is called whenever lexing reaches the start of a line
+	// outside any token.
+	onLine func(State)
+	// replay holds the tokens a lexer made by Tokens.Lexer returns instead
+	// of reading its source, and replayDiags their errors.
+	replay      []token.Token
+	replayDiags diag.List
 }
 
+type Mode uint
+
+const (
+	// ScanComments makes NextToken return comments as token.Comment
+	// instead of skipping them.
+	ScanComments Mode = 1 << iota
+)
+
 func New(src io.Reader) *Lexer {
+	return NewWithMode(src, 0)
+}
+
+func NewWithMode(src io.Reader, mode Mode) *Lexer {
 	return &Lexer{
-		src: bufio.NewReader(src),
+		src:   bufio.NewReader(src),
+		line:  1,
+		col:   1,
+		col16: 1,
+		mode:  mode,
 	}
 }
 
@@ -36,6 +74,7 @@ var keywords = map[string]token.TokenTag{
 	"elsif":    token.Elsif,
 	"else":     token.Else,
 	"while":    token.While,
+	"for":      token.For,
 	"break":    token.Break,
 	"continue": token.Continue,
 	"return":   token.Return,
@@ -60,6 +99,8 @@ var operators = map[string]token.TokenTag{
 	"/=": token.LetDiv,
 	"%=": token.LetMod,
 	"!":  token.Bang,
+	"&&": token.And,
+	"||": token.Or,
 	"->": token.Arrow,
 	",":  token.Comma,
 	":":  token.Colon,
@@ -85,69 +126,115 @@ type lexState int
 const (
 	initialState lexState = iota
 	commentState
-	zeroState
-	intState
-	floatState
+	numberState
+	stringQuoteState
+	stringQuote2State
 	stringState
 	stringEscState
+	stringHashState
+	tripleOpenState
+	tripleState
+	rawState
 	identState
 	operatorState
 )
 
 func (l *Lexer) NextToken() (token.Token, error) {
+	if l.replay != nil {
+		return l.nextReplayed()
+	}
+	if len(l.pending) > 0 {
+		d := l.pending[0]
+		l.pending = l.pending[1:]
+		return token.Token{}, d
+	}
+	if l.held != nil {
+		t := *l.held
+		l.held = nil
+		return t, nil
+	}
+
 	var buf []rune
 	t := token.Token{Tag: token.EOF}
 	state := initialState
+	lineEnded := false
+	// the escape sequence being read, and the state to go on with behind it
+	var esc []rune
+	var escLoc token.Location
+	var escReturn lexState
+	var ml multiline
 	err := func() error {
 		for {
-			line := l.line
-			col := l.col
+			line, col, offset, col16 := l.line, l.col, l.offset, l.col16
 			c, err := l.getc()
 			if err == io.EOF {
+				if state == initialState {
+					t.Location = token.Location{StartLine: line, StartColumn: col, StartOffset: offset, StartColumnUTF16: col16}
+					setEnd(&t.Location, line, col, offset, col16)
+				}
 				return nil
 			}
 			if err != nil {
 				return err
 			}
+			// the newline inserted at EOF takes no space
+			endOffset, endCol16 := l.offset, col16
+			if endOffset > offset {
+				endCol16 += utf16.RuneLen(c)
+			}
 			switch state {
 			case initialState:
 				if c != '\n' && unicode.IsSpace(c) {
 					continue
 				}
-				t.Location.StartLine = line
-				t.Location.StartColumn = col
-				t.Location.EndLine = line
-				t.Location.EndColumn = col
+				t.Location = token.Location{StartLine: line, StartColumn: col, StartOffset: offset, StartColumnUTF16: col16}
+				setEnd(&t.Location, line, col, endOffset, endCol16)
 				switch c {
 				case '\n':
 					if l.newlineRequired() {
 						t.Tag = token.Newline
 						t.Value = "\n"
+						lineEnded = true
 						return nil
 					}
+					l.lineStarted()
 				case '}':
+					if n := len(l.interps); n > 0 && l.interps[n-1] == 0 {
+						// the interpolation ends and the string goes on
+						l.interps = l.interps[:n-1]
+						t.Tag = token.StringEnd
+						state = stringState
+						buf = []rune{}
+						continue
+					}
 					t.Tag = token.RightBrace
 					t.Value = "}"
 					if l.newlineRequired() {
 						l.ungetc(c)
 						t.Tag = token.Newline
 						t.Value = "\n"
+					} else if n := len(l.interps); n > 0 {
+						l.interps[n-1]--
 					}
 					return nil
 				case '#':
+					if l.mode&ScanComments != 0 {
+						t.Tag = token.Comment
+						buf = []rune{c}
+					}
 					state = commentState
-				case '0':
-					t.Tag = token.IntLiteral
-					state = zeroState
 				case '"':
 					t.Tag = token.StringLiteral
-					state = stringState
+					state = stringQuoteState
+					buf = []rune{}
+				case '`':
+					t.Tag = token.StringLiteral
+					state = rawState
 					buf = []rune{}
 				default:
 					buf = []rune{c}
-					if unicode.IsDigit(c) {
-						t.Tag = token.IntLiteral
-						state = intState
+					if isDigit(c) {
+						state = numberState
 					} else if c == '_' || unicode.IsLetter(c) {
 						t.Tag = token.Identifier
 						state = identState
@@ -158,70 +245,163 @@ func (l *Lexer) NextToken() (token.Token, error) {
 			case commentState:
 				if c == '\n' {
 					l.ungetc(c)
+					if t.Tag == token.Comment {
+						return nil
+					}
 					state = initialState
+					continue
 				}
-			case zeroState:
-				if c != '.' {
-					l.ungetc(c)
-					t.Value = "0"
-					return nil
+				if t.Tag == token.Comment {
+					buf = append(buf, c)
+					setEnd(&t.Location, line, col, endOffset, endCol16)
 				}
-				buf = append(buf, '0')
-				buf = append(buf, '.')
-				t.Tag = token.FloatLiteral
-				state = floatState
-			case intState:
-				if c != '.' && !unicode.IsDigit(c) {
+			case numberState:
+				if !inNumber(buf, c) {
 					l.ungetc(c)
 					return nil
 				}
-				t.Location.EndLine = line
-				t.Location.EndColumn = col
+				setEnd(&t.Location, line, col, endOffset, endCol16)
 				buf = append(buf, c)
-				if c == '.' {
-					t.Tag = token.FloatLiteral
-					state = floatState
+			case stringQuoteState:
+				if c != '"' {
+					l.ungetc(c)
+					state = stringState
+					continue
 				}
-			case floatState:
-				if !unicode.IsDigit(c) {
+				setEnd(&t.Location, line, col, endOffset, endCol16)
+				state = stringQuote2State
+			case stringQuote2State:
+				if c != '"' {
+					// an empty string
 					l.ungetc(c)
+					state = initialState
 					return nil
 				}
-				t.Location.EndLine = line
-				t.Location.EndColumn = col
-				buf = append(buf, c)
+				setEnd(&t.Location, line, col, endOffset, endCol16)
+				state = tripleOpenState
 			case stringState:
 				switch c {
 				case '\\':
+					escLoc = token.Location{StartLine: line, StartColumn: col, StartOffset: offset, StartColumnUTF16: col16}
+					setEnd(&escLoc, line, col, endOffset, endCol16)
+					setEnd(&t.Location, line, col, endOffset, endCol16)
+					esc, escReturn = esc[:0], state
 					state = stringEscState
+				case '#':
+					setEnd(&t.Location, line, col, endOffset, endCol16)
+					state = stringHashState
 				case '"':
-					t.Location.EndLine = line
-					t.Location.EndColumn = col
+					setEnd(&t.Location, line, col, endOffset, endCol16)
 					state = initialState
 					return nil
 				default:
-					t.Location.EndLine = line
-					t.Location.EndColumn = col
+					setEnd(&t.Location, line, col, endOffset, endCol16)
 					buf = append(buf, c)
 				}
 			case stringEscState:
+				esc = append(esc, c)
+				r, ok, cut, msg := unescape(esc)
+				if cut {
+					l.ungetc(c)
+				} else {
+					setEnd(&escLoc, line, col, endOffset, endCol16)
+					setEnd(&t.Location, line, col, endOffset, endCol16)
+				}
+				if !ok {
+					continue
+				}
+				if msg != "" {
+					l.pending = append(l.pending, &diag.Diagnostic{
+						Loc:      escLoc,
+						Severity: diag.Error,
+						Code:     diag.InvalidEscape,
+						Message:  msg,
+					})
+				} else {
+					buf = append(buf, r)
+				}
+				state = escReturn
+			case stringHashState:
+				if c != '{' {
+					l.ungetc(c)
+					buf = append(buf, '#')
+					state = stringState
+					continue
+				}
+				setEnd(&t.Location, line, col, endOffset, endCol16)
+				if t.Tag == token.StringLiteral {
+					t.Tag = token.StringBegin
+				} else {
+					t.Tag = token.StringMiddle
+				}
+				l.interps = append(l.interps, 0)
+				state = initialState
+				return nil
+			case tripleOpenState:
+				// the text starts on the next line
+				switch c {
+				case ' ', '\t', '\r':
+					continue
+				case '\n':
+					ml.newLine(len(buf), l.line, l.col, l.offset, l.col16)
+					state = tripleState
+					continue
+				}
+				loc := token.Location{StartLine: line, StartColumn: col, StartOffset: offset, StartColumnUTF16: col16}
+				setEnd(&loc, line, col, endOffset, endCol16)
+				l.pending = append(l.pending, &diag.Diagnostic{
+					Loc:      loc,
+					Severity: diag.Error,
+					Code:     diag.InvalidMultiline,
+					Message:  `expect line break behind """`,
+				})
+				l.ungetc(c)
+				ml.newLine(len(buf), line, col, offset, col16)
+				state = tripleState
+			case tripleState:
+				setEnd(&t.Location, line, col, endOffset, endCol16)
+				if c == '"' {
+					ml.quotes++
+					if ml.quotes < 3 {
+						continue
+					}
+					var diags []*diag.Diagnostic
+					buf, diags = ml.dedent(buf)
+					l.pending = append(l.pending, diags...)
+					state = initialState
+					return nil
+				}
+				for ; ml.quotes > 0; ml.quotes-- {
+					ml.text('"')
+					buf = append(buf, '"')
+				}
+				ml.text(c)
 				switch c {
-				case 'n':
-					buf = append(buf, '\n')
+				case '\\':
+					escLoc = token.Location{StartLine: line, StartColumn: col, StartOffset: offset, StartColumnUTF16: col16}
+					setEnd(&escLoc, line, col, endOffset, endCol16)
+					esc, escReturn = esc[:0], state
+					state = stringEscState
+				case '\n':
+					buf = append(buf, c)
+					ml.newLine(len(buf), l.line, l.col, l.offset, l.col16)
 				default:
 					buf = append(buf, c)
 				}
-				t.Location.EndLine = line
-				t.Location.EndColumn = col
-				state = stringState
+			case rawState:
+				setEnd(&t.Location, line, col, endOffset, endCol16)
+				if c == '`' {
+					state = initialState
+					return nil
+				}
+				buf = append(buf, c)
 			case identState:
 				if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
 					l.ungetc(c)
 					return nil
 				}
 				buf = append(buf, c)
-				t.Location.EndLine = line
-				t.Location.EndColumn = col
+				setEnd(&t.Location, line, col, endOffset, endCol16)
 			case operatorState:
 				buf = append(buf, c)
 				if !isOperatorCandidate(string(buf)) {
@@ -229,8 +409,7 @@ func (l *Lexer) NextToken() (token.Token, error) {
 					buf = buf[:len(buf)-1]
 					return nil
 				}
-				t.Location.EndLine = line
-				t.Location.EndColumn = col
+				setEnd(&t.Location, line, col, endOffset, endCol16)
 			default:
 				panic("must not happen")
 			}
@@ -243,11 +422,31 @@ func (l *Lexer) NextToken() (token.Token, error) {
 		t.Value = string(buf)
 	}
 	switch state {
-	case stringState, stringEscState:
-		return token.Token{}, fmt.Errorf(
-			"%d:%d: unterminated string literal",
-			t.Location.StartLine, t.Location.StartColumn,
-		)
+	case stringQuoteState, stringState, stringEscState, stringHashState, tripleOpenState, tripleState, rawState:
+		l.pending = append(l.pending, &diag.Diagnostic{
+			Loc:      t.Location,
+			Severity: diag.Error,
+			Code:     diag.UnterminatedString,
+			Message:  "unterminated string literal",
+		})
+		return l.NextToken()
+	case numberState:
+		tag, at, n, msg := checkNumber(buf)
+		t.Tag = tag
+		if msg != "" {
+			// the literal is ASCII, one column and byte per rune
+			loc := t.Location
+			loc.StartColumn += at
+			loc.StartOffset += at
+			loc.StartColumnUTF16 += at
+			setEnd(&loc, loc.StartLine, loc.StartColumn+n-1, loc.StartOffset+n, loc.StartColumnUTF16+n)
+			l.pending = append(l.pending, &diag.Diagnostic{
+				Loc:      loc,
+				Severity: diag.Error,
+				Code:     diag.InvalidNumber,
+				Message:  msg,
+			})
+		}
 	case identState:
 		if v, ok := keywords[t.Value]; ok {
 			t.Tag = v
@@ -255,21 +454,59 @@ func (l *Lexer) NextToken() (token.Token, error) {
 	case operatorState:
 		v, ok := operators[t.Value]
 		if !ok {
-			return token.Token{}, fmt.Errorf(
-				"%d:%d: invalid character - '%c'",
-				t.Location.StartLine, t.Location.StartColumn, buf[0],
-			)
+			return token.Token{}, &diag.Diagnostic{
+				Loc:      t.Location,
+				Severity: diag.Error,
+				Code:     diag.InvalidCharacter,
+				Message:  fmt.Sprintf("invalid character - '%c'", buf[0]),
+			}
 		}
 		t.Tag = v
+		if n := len(l.interps); n > 0 && v == token.LeftBrace {
+			l.interps[n-1]++
+		}
+	}
+	// comments are transparent to the automatic newline insertion, and a
+	// malformed number literal ends a line as a valid one does
+	switch t.Tag {
+	case token.Comment:
+	case token.Invalid:
+		l.lastTag = token.IntLiteral
+	default:
+		l.lastTag = t.Tag
+	}
+	if lineEnded {
+		l.lineStarted()
+	}
+	if len(l.pending) > 0 {
+		l.held = &t
+		return l.NextToken()
 	}
-	l.lastTag = t.Tag
 	return t, nil
 }
 
+// setEnd makes loc end with the rune at line and col, which ends before
+// offset and the UTF-16 column col16.
+func setEnd(loc *token.Location, line, col, offset, col16 int) {
+	loc.EndLine = line
+	loc.EndColumn = col
+	loc.EndOffset = offset
+	loc.EndColumnUTF16 = col16
+}
+
+// lineStarted reports the state at the start of a line, unless the line
+// starts inside an interpolation, where there is no state to resume at.
+func (l *Lexer) lineStarted() {
+	if l.onLine != nil && len(l.interps) == 0 {
+		l.onLine(State{Line: l.line, Offset: l.offset, LastTag: l.lastTag})
+	}
+}
+
 var newlineRequesters = map[token.TokenTag]bool{
 	token.IntLiteral:    true,
 	token.FloatLiteral:  true,
 	token.StringLiteral: true,
+	token.StringEnd:     true,
 	token.Identifier:    true,
 
 	token.RightParen:   true,
@@ -285,39 +522,52 @@ var newlineRequesters = map[token.TokenTag]bool{
 }
 
 func (l *Lexer) newlineRequired() bool {
+	if n := len(l.interps); n > 0 && l.interps[n-1] == 0 {
+		// an interpolation holds a single expression, which may span lines
+		return false
+	}
 	_, ok := newlineRequesters[l.lastTag]
 	return ok
 }
 
 // This method does'nt regard to rune buffering.
 // DO NOT call directly.
-func (l *Lexer) srcGetc() (c rune, err error) {
-	c, _, err = l.src.ReadRune()
+func (l *Lexer) srcGetc() (c rune, size int, err error) {
+	c, size, err = l.src.ReadRune()
 	if err == io.EOF && l.srcLastRune != '\n' {
 		// insert newline when there are no newline just before EOF.
 		c = '\n'
 		err = nil
 	}
-	l.srcLastRune = c
+	if err == nil {
+		l.srcLastRune = c
+	}
 	return
 }
 
 func (l *Lexer) getc() (c rune, err error) {
+	size := 0
 	if l.hasSavedRune {
 		l.hasSavedRune = false
 		c = l.savedRune
+		size = l.savedSize
 	} else {
-		c, err = l.srcGetc()
+		c, size, err = l.srcGetc()
 	}
 	if err != nil {
 		return
 	}
+	l.lastSize = size
+	l.offset += size
 	if c == '\n' {
 		l.lastNlCol = l.col
+		l.lastNlCol16 = l.col16
 		l.line++
 		l.col = 1
+		l.col16 = 1
 	} else {
 		l.col++
+		l.col16 += utf16.RuneLen(c)
 	}
 	return
 }
@@ -325,9 +575,13 @@ func (l *Lexer) getc() (c rune, err error) {
 func (l *Lexer) ungetc(c rune) {
 	l.hasSavedRune = true
 	l.savedRune = c
+	l.savedSize = l.lastSize
+	l.offset -= l.lastSize
 	l.col--
+	l.col16 -= utf16.RuneLen(c)
 	if c == '\n' {
 		l.line--
 		l.col = l.lastNlCol
+		l.col16 = l.lastNlCol16
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/arikui1911/goore/diag"
 	"github.com/arikui1911/goore/lexer"
 	"github.com/arikui1911/goore/token"
 )
@@ -28,6 +29,7 @@ func TestLexFirstToken(t *testing.T) {
 		{"elsif", `elsif`, token.Elsif, "elsif"},
 		{"else", `else`, token.Else, "else"},
 		{"while", `while`, token.While, "while"},
+		{"for", `for`, token.For, "for"},
 		{"break", `break`, token.Break, "break"},
 		{"continue", `continue`, token.Continue, "continue"},
 		{"return", `return`, token.Return, "return"},
@@ -49,6 +51,8 @@ func TestLexFirstToken(t *testing.T) {
 		{"let div", `/=`, token.LetDiv, "/="},
 		{"let mod", `%=`, token.LetMod, "%="},
 		{"bang", `!`, token.Bang, "!"},
+		{"and", `&&`, token.And, "&&"},
+		{"or", `||`, token.Or, "||"},
 		{"arrow", `->`, token.Arrow, "->"},
 		{"comma", `,`, token.Comma, ","},
 		{"colon", `:`, token.Colon, ":"},
@@ -104,6 +108,252 @@ func TestLexIdentifiers(t *testing.T) {
 	})
 }
 
+func TestLexComments(t *testing.T) {
+	src := "# head\nx # tail\n# last"
+	seq := []struct {
+		name string
+		tag  token.TokenTag
+		val  string
+	}{
+		{"leading comment", token.Comment, "# head"},
+		{"identifier", token.Identifier, "x"},
+		{"trailing comment", token.Comment, "# tail"},
+		{"newline after comment", token.Newline, "\n"},
+		{"comment before EOF", token.Comment, "# last"},
+		{"EOF", token.EOF, ""},
+	}
+
+	l := lexer.NewWithMode(strings.NewReader(src), lexer.ScanComments)
+	for _, e := range seq {
+		t.Run(e.name, func(t *testing.T) {
+			testNextTokenTagAndValue(t, l, e.tag, e.val)
+		})
+	}
+
+	t.Run("skipped by default", func(t *testing.T) {
+		l := lexer.New(strings.NewReader(src))
+		testNextTokenTagAndValue(t, l, token.Identifier, "x")
+		testNextTokenTagAndValue(t, l, token.Newline, "\n")
+		testNextTokenTagAndValue(t, l, token.EOF, "")
+	})
+}
+
+func TestLexInterpolation(t *testing.T) {
+	src := "\"a #{b + \"c#{d}\"} e#{ {1: f}[1]\n} #g\\#{h}\""
+	seq := []struct {
+		tag token.TokenTag
+		val string
+		loc string
+	}{
+		{token.StringBegin, "a ", "(1:1):(1:5)"},
+		{token.Identifier, "b", "(1:6):(1:6)"},
+		{token.Add, "+", "(1:8):(1:8)"},
+		{token.StringBegin, "c", "(1:10):(1:13)"},
+		{token.Identifier, "d", "(1:14):(1:14)"},
+		{token.StringEnd, "", "(1:15):(1:16)"},
+		{token.StringMiddle, " e", "(1:17):(1:21)"},
+		{token.LeftBrace, "{", "(1:23):(1:23)"},
+		{token.IntLiteral, "1", "(1:24):(1:24)"},
+		{token.Colon, ":", "(1:25):(1:25)"},
+		{token.Identifier, "f", "(1:27):(1:27)"},
+		{token.Newline, "\n", "(1:28):(1:28)"},
+		{token.RightBrace, "}", "(1:28):(1:28)"},
+		{token.LeftBracket, "[", "(1:29):(1:29)"},
+		{token.IntLiteral, "1", "(1:30):(1:30)"},
+		{token.RightBracket, "]", "(1:31):(1:31)"},
+		// the interpolation takes no newline
+		{token.StringEnd, " #g#{h}", "(2:1):(2:10)"},
+		{token.Newline, "\n", "(2:11):(2:11)"},
+		{token.EOF, "", "(3:1):(3:1)"},
+	}
+
+	l := lexer.New(strings.NewReader(src))
+	for _, e := range seq {
+		r, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Tag != e.tag || r.Value != e.val || r.Location.String() != e.loc {
+			t.Errorf("want <%s %#v %s> got <%s %#v %s>", e.tag, e.val, e.loc, r.Tag, r.Value, r.Location)
+		}
+	}
+}
+
+func TestLexStrings(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		val  string
+		loc  string
+	}{
+		{"escapes", `"\t\r\0\\\"\#{x}\n"`, "\t\r\x00\\\"#{x}\n", "(1:1):(1:19)"},
+		{"hex escape", `"\x41\x7f"`, "A\x7f", "(1:1):(1:10)"},
+		{"unicode escape", `"\u{e9}\u{1F600}"`, "é😀", "(1:1):(1:17)"},
+		{"empty", `""`, "", "(1:1):(1:2)"},
+		{"raw", "`a\\n#{b}\n\\`", "a\\n#{b}\n\\", "(1:1):(2:2)"},
+		{"multiline", "\"\"\"\n  a\n    b\n\n  \"c\"\n  \"\"\"", "a\n  b\n\n\"c\"", "(1:1):(6:5)"},
+		{"multiline escapes", "\"\"\" \n\t\\tx\\n\n\t\"\"\"", "\tx\n", "(1:1):(3:4)"},
+		{"multiline without indentation", "\"\"\"\na\n  b\"\"\"", "a\n  b", "(1:1):(3:6)"},
+		{"multiline empty", "\"\"\"\n\"\"\"", "", "(1:1):(2:3)"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			l := lexer.New(strings.NewReader(d.src))
+			r, err := l.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Tag != token.StringLiteral || r.Value != d.val || r.Location.String() != d.loc {
+				t.Errorf("want <%s %#v %s> got <%s %#v %s>", token.StringLiteral, d.val, d.loc, r.Tag, r.Value, r.Location)
+			}
+		})
+	}
+}
+
+func TestLexStringErrors(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		code diag.Code
+		loc  string
+		val  string
+	}{
+		{"unknown escape", `"a\qb"`, diag.InvalidEscape, "(1:3):(1:4)", "ab"},
+		{"short hex escape", `"\x4"`, diag.InvalidEscape, "(1:2):(1:4)", ""},
+		{"hex escape out of range", `"\xff"`, diag.InvalidEscape, "(1:2):(1:5)", ""},
+		{"unicode escape without braces", `"\u41"`, diag.InvalidEscape, "(1:2):(1:3)", "41"},
+		{"surrogate", `"\u{D800}"`, diag.InvalidEscape, "(1:2):(1:9)", ""},
+		{"long unicode escape", `"\u{1000000}"`, diag.InvalidEscape, "(1:2):(1:10)", "0}"},
+		{"text behind opening quotes", "\"\"\"a\n\"\"\"", diag.InvalidMultiline, "(1:4):(1:4)", "a"},
+		{"line less indented", "\"\"\"\n  a\n b\n  \"\"\"", diag.InvalidMultiline, "(3:2):(3:2)", "a\n b"},
+		{"unterminated raw", "`a\n", diag.UnterminatedString, "(1:1):(1:3)", ""},
+		{"unterminated multiline", "\"\"\"\na\"\"", diag.UnterminatedString, "(1:1):(2:4)", ""},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			l := lexer.New(strings.NewReader(d.src))
+			_, err := l.NextToken()
+			got, ok := err.(*diag.Diagnostic)
+			if !ok {
+				t.Fatalf("want diagnostic got <%v>", err)
+			}
+			if got.Code != d.code || got.Loc.String() != d.loc {
+				t.Errorf("want <%v %v> got <%v %v>", d.code, d.loc, got.Code, got.Loc)
+			}
+			if d.code == diag.UnterminatedString {
+				return
+			}
+			// the string follows its diagnostic
+			r, err := l.NextToken()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Tag != token.StringLiteral || r.Value != d.val {
+				t.Errorf("want <%s %#v> got <%s %#v>", token.StringLiteral, d.val, r.Tag, r.Value)
+			}
+		})
+	}
+}
+
+func TestLexNumbers(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		tag  token.TokenTag
+		val  string
+	}{
+		{"hex", `0x1F`, token.IntLiteral, "0x1F"},
+		{"upper hex", `0XfF`, token.IntLiteral, "0XfF"},
+		{"octal", `0o17`, token.IntLiteral, "0o17"},
+		{"binary", `0b1010`, token.IntLiteral, "0b1010"},
+		{"separators", `1_000_000`, token.IntLiteral, "1_000_000"},
+		{"hex separators", `0xFF_FF`, token.IntLiteral, "0xFF_FF"},
+		{"exponent", `1e9`, token.FloatLiteral, "1e9"},
+		{"negative exponent", `2.5e-3`, token.FloatLiteral, "2.5e-3"},
+		{"positive exponent", `1E+7`, token.FloatLiteral, "1E+7"},
+		{"float separators", `1_0.2_5`, token.FloatLiteral, "1_0.2_5"},
+		{"zero fraction", `0.5`, token.FloatLiteral, "0.5"},
+		{"stops at second dot", `1.5.3`, token.FloatLiteral, "1.5"},
+		{"stops at operator", `1e3-1`, token.FloatLiteral, "1e3"},
+	}
+
+	for _, e := range table {
+		t.Run(e.name, func(t *testing.T) {
+			l := lexer.New(strings.NewReader(e.src))
+			testNextTokenTagAndValue(t, l, e.tag, e.val)
+		})
+	}
+}
+
+func TestLexNumberErrors(t *testing.T) {
+	table := []struct {
+		src string
+		msg string
+		loc string
+	}{
+		{`0x`, "hexadecimal literal has no digits", "(1:1):(1:2)"},
+		{`0b`, "binary literal has no digits", "(1:1):(1:2)"},
+		{`1__0`, "'_' must separate successive digits", "(1:2):(1:2)"},
+		{`1_`, "'_' must separate successive digits", "(1:2):(1:2)"},
+		{`0x_1`, "'_' must separate successive digits", "(1:3):(1:3)"},
+		{`1_.5`, "'_' must separate successive digits", "(1:2):(1:2)"},
+		{`1.`, "expect digit behind '.'", "(1:2):(1:2)"},
+		{`1.e5`, "expect digit behind '.'", "(1:2):(1:2)"},
+		{`1e`, "exponent has no digits", "(1:2):(1:2)"},
+		{`1e+`, "exponent has no digits", "(1:2):(1:3)"},
+		{`012`, "decimal literal must not start with 0", "(1:1):(1:1)"},
+		{`0b102`, "invalid digit '2' in binary literal", "(1:5):(1:5)"},
+		{`0o8`, "invalid digit '8' in octal literal", "(1:3):(1:3)"},
+		{`12ab`, "invalid digit 'a' in decimal literal", "(1:3):(1:3)"},
+	}
+
+	for _, d := range table {
+		t.Run(d.src, func(t *testing.T) {
+			l := lexer.New(strings.NewReader(d.src))
+			_, err := l.NextToken()
+			got, ok := err.(*diag.Diagnostic)
+			if !ok {
+				t.Fatalf("want diagnostic got <%v>", err)
+			}
+			if got.Code != diag.InvalidNumber || got.Message != d.msg || got.Loc.String() != d.loc {
+				t.Errorf("want <%v %v %v> got <%v %v %v>", diag.InvalidNumber, d.msg, d.loc, got.Code, got.Message, got.Loc)
+			}
+			// the whole literal follows its diagnostic
+			testNextTokenTagAndValue(t, l, token.Invalid, d.src)
+			testNextTokenTagAndValue(t, l, token.Newline, "\n")
+		})
+	}
+}
+
+func TestLexLocations(t *testing.T) {
+	table := []struct {
+		tag token.TokenTag
+		loc string
+	}{
+		{token.Identifier, "(1:1):(1:1)"},
+		{token.Let, "(1:3):(1:3)"},
+		{token.IntLiteral, "(2:3):(2:4)"},
+		// the newline inserted at EOF
+		{token.Newline, "(2:5):(2:5)"},
+		{token.EOF, "(3:1):(3:1)"},
+		// EOF stays without another newline
+		{token.EOF, "(3:1):(3:1)"},
+	}
+
+	l := lexer.New(strings.NewReader("x =\n  12"))
+	for _, d := range table {
+		r, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Tag != d.tag || r.Location.String() != d.loc {
+			t.Errorf("want <%s %s> got <%s %s>", d.tag, d.loc, r.Tag, r.Location)
+		}
+	}
+}
+
 func testNextTokenTagAndValue(t *testing.T, l *lexer.Lexer, tag token.TokenTag, val string) {
 	r, err := l.NextToken()
 	if err != nil {
@@ -119,3 +369,34 @@ func testNextTokenTagAndValue(t *testing.T, l *lexer.Lexer, tag token.TokenTag,
 		return
 	}
 }
+
+func TestLexOffsets(t *testing.T) {
+	src := "héllo = \"𝄞x\"\n  42"
+	seq := []struct {
+		tag token.TokenTag
+		loc token.Location
+	}{
+		{token.Identifier, token.Location{StartLine: 1, StartColumn: 1, EndLine: 1, EndColumn: 5, StartOffset: 0, EndOffset: 6, StartColumnUTF16: 1, EndColumnUTF16: 6}},
+		{token.Let, token.Location{StartLine: 1, StartColumn: 7, EndLine: 1, EndColumn: 7, StartOffset: 7, EndOffset: 8, StartColumnUTF16: 7, EndColumnUTF16: 8}},
+		{token.StringLiteral, token.Location{StartLine: 1, StartColumn: 9, EndLine: 1, EndColumn: 12, StartOffset: 9, EndOffset: 16, StartColumnUTF16: 9, EndColumnUTF16: 14}},
+		{token.Newline, token.Location{StartLine: 1, StartColumn: 13, EndLine: 1, EndColumn: 13, StartOffset: 16, EndOffset: 17, StartColumnUTF16: 14, EndColumnUTF16: 15}},
+		{token.IntLiteral, token.Location{StartLine: 2, StartColumn: 3, EndLine: 2, EndColumn: 4, StartOffset: 19, EndOffset: 21, StartColumnUTF16: 3, EndColumnUTF16: 5}},
+		// the newline inserted at EOF is empty
+		{token.Newline, token.Location{StartLine: 2, StartColumn: 5, EndLine: 2, EndColumn: 5, StartOffset: 21, EndOffset: 21, StartColumnUTF16: 5, EndColumnUTF16: 5}},
+		{token.EOF, token.Location{StartLine: 3, StartColumn: 1, EndLine: 3, EndColumn: 1, StartOffset: 21, EndOffset: 21, StartColumnUTF16: 1, EndColumnUTF16: 1}},
+	}
+
+	l := lexer.New(strings.NewReader(src))
+	for _, e := range seq {
+		r, err := l.NextToken()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Tag != e.tag || r.Location != e.loc {
+			t.Errorf("want <%s %+v> got <%s %+v>", e.tag, e.loc, r.Tag, r.Location)
+		}
+		if r.Tag != token.EOF && r.Tag != token.Newline && src[r.Location.StartOffset:r.Location.EndOffset] == "" {
+			t.Errorf("%s: empty source text", r)
+		}
+	}
+}
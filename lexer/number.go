@@ -0,0 +1,113 @@
+package lexer
+
+import (
+	"fmt"
+
+	"github.com/arikui1911/goore/token"
+)
+
+var baseNames = map[int]string{
+	2:  "binary",
+	8:  "octal",
+	10: "decimal",
+	16: "hexadecimal",
+}
+
+func isDigit(c rune) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isDigitOf(c rune, base int) bool {
+	switch base {
+	case 2:
+		return c == '0' || c == '1'
+	case 8:
+		return '0' <= c && c <= '7'
+	case 16:
+		return isHexDigit(c)
+	}
+	return isDigit(c)
+}
+
+// numberBase returns the base of the number literal s and the length of
+// its prefix.
+func numberBase(s []rune) (int, int) {
+	if len(s) < 2 || s[0] != '0' {
+		return 10, 0
+	}
+	switch s[1] {
+	case 'x', 'X':
+		return 16, 2
+	case 'o', 'O':
+		return 8, 2
+	case 'b', 'B':
+		return 2, 2
+	}
+	return 10, 0
+}
+
+// inNumber tells whether c goes on the number literal read as buf so far.
+// It takes in any letter, digit or underscore so that a malformed literal
+// is a single token; only decimal literals have a fraction and a signed
+// exponent.
+func inNumber(buf []rune, c rune) bool {
+	switch {
+	case isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || c == '_':
+		return true
+	case c == '.':
+		base, _ := numberBase(buf)
+		for _, r := range buf {
+			if r == '.' || r == 'e' || r == 'E' {
+				return false
+			}
+		}
+		return base == 10
+	case c == '+' || c == '-':
+		base, _ := numberBase(buf)
+		last := buf[len(buf)-1]
+		return base == 10 && (last == 'e' || last == 'E')
+	}
+	return false
+}
+
+// checkNumber tells whether the number literal s is an int or a float.
+// A malformed literal is Invalid and comes with a message about the n runes
+// from s[at].
+func checkNumber(s []rune) (tag token.TokenTag, at int, n int, msg string) {
+	base, prefix := numberBase(s)
+	if prefix == len(s) {
+		return token.Invalid, 0, len(s), fmt.Sprintf("%s literal has no digits", baseNames[base])
+	}
+	if base == 10 && len(s) > 1 && s[0] == '0' && (isDigit(s[1]) || s[1] == '_') {
+		return token.Invalid, 0, 1, "decimal literal must not start with 0"
+	}
+	tag = token.IntLiteral
+	for i := prefix; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case isDigitOf(c, base):
+		case c == '_':
+			if i == prefix || !isDigitOf(s[i-1], base) || i+1 == len(s) || !isDigitOf(s[i+1], base) {
+				return token.Invalid, i, 1, "'_' must separate successive digits"
+			}
+		case c == '.':
+			tag = token.FloatLiteral
+			if i+1 == len(s) || !isDigit(s[i+1]) {
+				return token.Invalid, i, 1, "expect digit behind '.'"
+			}
+		case base == 10 && (c == 'e' || c == 'E'):
+			tag = token.FloatLiteral
+			j := i + 1
+			if j < len(s) && (s[j] == '+' || s[j] == '-') {
+				j++
+			}
+			if j == len(s) || !isDigit(s[j]) {
+				return token.Invalid, i, j - i, "exponent has no digits"
+			}
+			i = j - 1
+		default:
+			return token.Invalid, i, 1, fmt.Sprintf("invalid digit '%c' in %s literal", c, baseNames[base])
+		}
+	}
+	return tag, 0, 0, ""
+}
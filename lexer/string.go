@@ -0,0 +1,154 @@
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/token"
+)
+
+var simpleEscapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'"':  '"',
+	'#':  '#',
+}
+
+// unescape decodes the escape sequence seq, the runes read behind a
+// backslash so far. It returns ok false while the sequence goes on. An
+// invalid sequence comes with a message; cut tells that its last rune is
+// not part of it but left to the string.
+func unescape(seq []rune) (r rune, ok bool, cut bool, msg string) {
+	c := seq[len(seq)-1]
+	switch seq[0] {
+	case 'x':
+		// \xHH, ASCII only as larger values are no single byte
+		if len(seq) > 1 && !isHexDigit(c) {
+			return 0, true, true, fmt.Sprintf(`invalid escape sequence - \%s`, string(seq[:len(seq)-1]))
+		}
+		if len(seq) < 3 {
+			return 0, false, false, ""
+		}
+		v, _ := strconv.ParseUint(string(seq[1:]), 16, 8)
+		if v > utf8.RuneSelf-1 {
+			return 0, true, false, fmt.Sprintf(`escape sequence out of range - \%s`, string(seq))
+		}
+		return rune(v), true, false, ""
+	case 'u':
+		// \u{H...} with up to six digits
+		switch {
+		case len(seq) == 1:
+			return 0, false, false, ""
+		case len(seq) == 2 && c != '{':
+			return 0, true, true, `invalid escape sequence - \u`
+		case len(seq) == 2:
+			return 0, false, false, ""
+		case c == '}' && len(seq) > 3:
+			v, _ := strconv.ParseUint(string(seq[2:len(seq)-1]), 16, 32)
+			if !utf8.ValidRune(rune(v)) {
+				return 0, true, false, fmt.Sprintf(`invalid code point - \%s`, string(seq))
+			}
+			return rune(v), true, false, ""
+		case isHexDigit(c) && len(seq) <= 8:
+			return 0, false, false, ""
+		}
+		return 0, true, true, fmt.Sprintf(`invalid escape sequence - \%s`, string(seq[:len(seq)-1]))
+	}
+	if r, ok := simpleEscapes[c]; ok {
+		return r, true, false, ""
+	}
+	return 0, true, false, fmt.Sprintf(`unknown escape sequence - \%c`, c)
+}
+
+func isHexDigit(c rune) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// multiline keeps track of the lines of a triple-quoted string, whose
+// indentation is known only at its end.
+type multiline struct {
+	lines  []multilineLine
+	inLead bool
+	// quotes counts the quotes read in a row, which may close the string.
+	quotes int
+}
+
+type multilineLine struct {
+	// start is where the line starts in the value.
+	start int
+	// lead is the number of blanks the line starts with in the source,
+	// not counting those from escape sequences.
+	lead int
+	// first is the source rune behind the lead.
+	first rune
+	// where the line starts in the source
+	line   int
+	col    int
+	offset int
+	col16  int
+}
+
+func (m *multiline) newLine(start, line, col, offset, col16 int) {
+	m.lines = append(m.lines, multilineLine{start: start, line: line, col: col, offset: offset, col16: col16})
+	m.inLead = true
+}
+
+// text notes the rune c read in the source.
+func (m *multiline) text(c rune) {
+	if !m.inLead {
+		return
+	}
+	ln := &m.lines[len(m.lines)-1]
+	if c == ' ' || c == '\t' {
+		ln.lead++
+		return
+	}
+	ln.first = c
+	m.inLead = false
+}
+
+// dedent strips the indentation of the closing line, the blanks before
+// the closing quotes, from the lines of buf, which lose the closing line
+// itself along with the line break before it. A closing line with any
+// other text strips nothing. Lines that do not start with the indentation
+// are reported, except blank ones, which only keep their line break.
+func (m *multiline) dedent(buf []rune) ([]rune, []*diag.Diagnostic) {
+	last := m.lines[len(m.lines)-1]
+	indent := buf[last.start:]
+	if last.lead != len(indent) {
+		return buf, nil
+	}
+	var out []rune
+	var diags []*diag.Diagnostic
+	for i, ln := range m.lines[:len(m.lines)-1] {
+		text := buf[ln.start:m.lines[i+1].start]
+		switch {
+		case ln.lead >= len(indent) && string(text[:len(indent)]) == string(indent):
+			text = text[len(indent):]
+		case ln.lead == len(text)-1:
+			text = text[ln.lead:]
+		default:
+			// the blanks of the line are ASCII, one column and byte each
+			col, offset, col16 := ln.col+ln.lead, ln.offset+ln.lead, ln.col16+ln.lead
+			loc := token.Location{StartLine: ln.line, StartColumn: col, StartOffset: offset, StartColumnUTF16: col16}
+			setEnd(&loc, ln.line, col, offset+utf8.RuneLen(ln.first), col16+utf16.RuneLen(ln.first))
+			diags = append(diags, &diag.Diagnostic{
+				Loc:      loc,
+				Severity: diag.Error,
+				Code:     diag.InvalidMultiline,
+				Message:  "line of multiline string not indented like its closing quotes",
+			})
+		}
+		out = append(out, text...)
+	}
+	if len(out) > 0 {
+		out = out[:len(out)-1]
+	}
+	return append([]rune{}, out...), diags
+}
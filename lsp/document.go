@@ -0,0 +1,239 @@
+package lsp
+
+import (
+	"net/url"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/lexer"
+	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/token"
+)
+
+// document is an open text document together with its tokens and the
+// result of parsing them.
+type document struct {
+	uri        string
+	fileName   string
+	version    int
+	text       string
+	lineStarts []int
+	tokens     *lexer.Tokens
+	prog       *ast.Program
+	err        error
+	index      *index
+}
+
+func newDocument(uri string, version int, text string) *document {
+	d := &document{uri: uri, fileName: fileNameOf(uri), version: version}
+	d.setText(text)
+	return d
+}
+
+func fileNameOf(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return u.Path
+}
+
+// setText replaces the whole text and lexes and parses it again.
+func (d *document) setText(text string) {
+	d.tokens = lexer.Lex(text, 0)
+	d.parse()
+}
+
+// parse parses the tokens again as a whole, without lexing the text.
+func (d *document) parse() {
+	d.text = d.tokens.Source()
+	d.lineStarts = lineStartsOf(d.text)
+	d.prog, d.err = parser.New(d.tokens.Lexer(), d.fileName).Parse()
+	d.index = newIndex(d.prog)
+}
+
+// apply applies the changes of a didChange notification in order. A change
+// without a range replaces the whole text. A change with one re-lexes only
+// the lines around it; the tokens are parsed once, after all changes.
+func (d *document) apply(changes []TextDocumentContentChangeEvent) {
+	old := d.text
+	for _, c := range changes {
+		if c.Range == nil {
+			d.tokens = lexer.Lex(c.Text, 0)
+			continue
+		}
+		text := d.tokens.Source()
+		tmp := &document{text: text, lineStarts: lineStartsOf(text)}
+		beg := tmp.offset(c.Range.Start)
+		end := tmp.offset(c.Range.End)
+		// the tokens are updated by whole lines
+		startLine := tmp.lineOf(beg)
+		endLine := tmp.lineOf(end) + 1
+		lineEnd := len(text)
+		if endLine <= len(tmp.lineStarts) {
+			lineEnd = tmp.lineStarts[endLine-1]
+		}
+		lines := text[tmp.lineStarts[startLine-1]:beg] + c.Text + text[end:lineEnd]
+		if _, err := d.tokens.Update(startLine, endLine, lines); err != nil {
+			d.tokens = lexer.Lex(text[:beg]+c.Text+text[end:], 0)
+		}
+	}
+	if d.tokens.Source() != old {
+		d.parse()
+	}
+}
+
+// lineOf returns the one based line of the byte offset off.
+func (d *document) lineOf(off int) int {
+	return sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > off })
+}
+
+func lineStartsOf(text string) []int {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func (d *document) line(i int) string {
+	beg := d.lineStarts[i]
+	if i+1 < len(d.lineStarts) {
+		return d.text[beg : d.lineStarts[i+1]-1]
+	}
+	return d.text[beg:]
+}
+
+// offset returns the byte offset of pos, clamped to the text.
+func (d *document) offset(pos Position) int {
+	if pos.Line < 0 {
+		return 0
+	}
+	if pos.Line >= len(d.lineStarts) {
+		return len(d.text)
+	}
+	line := d.line(pos.Line)
+	n := 0
+	for i, r := range line {
+		if n >= pos.Character {
+			return d.lineStarts[pos.Line] + i
+		}
+		n += utf16Len(r)
+	}
+	return d.lineStarts[pos.Line] + len(line)
+}
+
+// position converts a one based goore line and rune column to an LSP
+// position.
+func (d *document) position(line int, col int) Position {
+	l := max(line-1, 0)
+	if l >= len(d.lineStarts) {
+		l = len(d.lineStarts) - 1
+		return Position{Line: l, Character: utf16Count(d.line(l))}
+	}
+	text := d.line(l)
+	n := 0
+	for _, r := range text {
+		if col <= 1 {
+			break
+		}
+		n += utf16Len(r)
+		col--
+	}
+	return Position{Line: l, Character: n}
+}
+
+// lineColumn converts an LSP position to a one based goore line and rune
+// column.
+func (d *document) lineColumn(pos Position) (int, int) {
+	if pos.Line < 0 || pos.Line >= len(d.lineStarts) {
+		return pos.Line + 1, 1
+	}
+	col := 1
+	n := 0
+	for _, r := range d.line(pos.Line) {
+		if n >= pos.Character {
+			break
+		}
+		n += utf16Len(r)
+		col++
+	}
+	return pos.Line + 1, col
+}
+
+// rangeOf converts loc to an LSP range, from its UTF-16 columns when the
+// lexer set them and else from its rune columns, the end one inclusive.
+func (d *document) rangeOf(loc *token.Location) Range {
+	if loc.StartColumnUTF16 > 0 && loc.EndColumnUTF16 > 0 {
+		return Range{
+			Start: Position{Line: loc.StartLine - 1, Character: loc.StartColumnUTF16 - 1},
+			End:   Position{Line: loc.EndLine - 1, Character: loc.EndColumnUTF16 - 1},
+		}
+	}
+	return Range{
+		Start: d.position(loc.StartLine, loc.StartColumn),
+		End:   d.position(loc.EndLine, loc.EndColumn+1),
+	}
+}
+
+func (d *document) fullRange() Range {
+	last := len(d.lineStarts) - 1
+	return Range{End: Position{Line: last, Character: utf16Count(d.line(last))}}
+}
+
+func utf16Len(r rune) int {
+	if r >= 0x10000 && utf8.ValidRune(r) {
+		return 2
+	}
+	return 1
+}
+
+func utf16Count(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16Len(r)
+	}
+	return n
+}
+
+// diagnostics reports the syntax errors of the document, including the
+// error that stopped parsing if any.
+func (d *document) diagnostics() []Diagnostic {
+	diags := []Diagnostic{}
+	if d.prog != nil {
+		for _, e := range d.prog.Diagnostics {
+			diags = append(diags, d.diagnostic(e))
+		}
+	}
+	if d.err != nil {
+		e, ok := d.err.(*diag.Diagnostic)
+		if !ok {
+			e = &diag.Diagnostic{
+				Loc:     token.Location{StartLine: 1, StartColumn: 1, EndLine: 1, EndColumn: 1},
+				Message: d.err.Error(),
+			}
+		}
+		diags = append(diags, d.diagnostic(e))
+	}
+	return diags
+}
+
+var severities = map[diag.Severity]DiagnosticSeverity{
+	diag.Error:   SeverityError,
+	diag.Warning: SeverityWarning,
+	diag.Note:    SeverityInformation,
+}
+
+func (d *document) diagnostic(e *diag.Diagnostic) Diagnostic {
+	return Diagnostic{
+		Range:    d.rangeOf(&e.Loc),
+		Severity: severities[e.Severity],
+		Code:     string(e.Code),
+		Source:   "goore",
+		Message:  e.Message,
+	}
+}
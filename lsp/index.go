@@ -0,0 +1,188 @@
+package lsp
+
+import (
+	"github.com/arikui1911/goore/ast"
+)
+
+type symbolKind int
+
+const (
+	defSymbol symbolKind = iota
+	paramSymbol
+	loopVarSymbol
+)
+
+// symbol is a name introduced by a def statement, a function parameter or
+// a variable of a for loop.
+type symbol struct {
+	kind symbolKind
+	name *ast.Identifier
+	refs []*ast.Identifier
+}
+
+// index maps the identifiers of a program to the symbols they denote.
+type index struct {
+	uses map[*ast.Identifier]*symbol
+}
+
+type scope struct {
+	names map[string]*symbol
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{names: map[string]*symbol{}, outer: outer}
+}
+
+func (s *scope) lookup(name string) *symbol {
+	for ; s != nil; s = s.outer {
+		if sym, ok := s.names[name]; ok {
+			return sym
+		}
+	}
+	return nil
+}
+
+// newIndex resolves the identifiers of prog the way the interpreter looks
+// them up. Function bodies run after the enclosing code has defined its
+// names, so they are resolved last, against the complete scopes.
+func newIndex(prog *ast.Program) *index {
+	x := &index{uses: map[*ast.Identifier]*symbol{}}
+	if prog == nil {
+		return x
+	}
+	r := &resolver{index: x}
+	r.statements(newScope(nil), prog.Statements)
+	for len(r.deferred) > 0 {
+		fn := r.deferred[0]
+		r.deferred = r.deferred[1:]
+		fn()
+	}
+	return x
+}
+
+// at returns the symbol of the identifier at the one based line and
+// column, also matching the column just behind the identifier.
+func (x *index) at(line int, col int) (*ast.Identifier, *symbol) {
+	for id, sym := range x.uses {
+		loc := id.Loc
+		if loc.StartLine == line && loc.StartColumn <= col && col <= loc.EndColumn+1 {
+			return id, sym
+		}
+	}
+	return nil, nil
+}
+
+type resolver struct {
+	index    *index
+	deferred []func()
+}
+
+func (r *resolver) declare(s *scope, kind symbolKind, name *ast.Identifier) {
+	sym := &symbol{kind: kind, name: name}
+	s.names[name.Name] = sym
+	r.index.uses[name] = sym
+}
+
+func (r *resolver) use(s *scope, id *ast.Identifier) {
+	if _, ok := r.index.uses[id]; ok {
+		// the target of a compound assignment is shared with its operand
+		return
+	}
+	sym := s.lookup(id.Name)
+	if sym == nil {
+		return
+	}
+	sym.refs = append(sym.refs, id)
+	r.index.uses[id] = sym
+}
+
+func (r *resolver) statements(s *scope, stmts []ast.Statement) {
+	for _, stmt := range stmts {
+		r.statement(s, stmt)
+	}
+}
+
+func (r *resolver) statement(s *scope, stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.Def:
+		if stmt.Init != nil {
+			r.expression(s, stmt.Init)
+		}
+		r.declare(s, defSymbol, stmt.Name)
+	case *ast.While:
+		r.expression(s, stmt.Cond)
+		r.statements(newScope(s), stmt.Body)
+	case *ast.For:
+		r.expression(s, stmt.Iterable)
+		bs := newScope(s)
+		for _, v := range stmt.Vars {
+			r.declare(bs, loopVarSymbol, v)
+		}
+		r.statements(bs, stmt.Body)
+	case *ast.Return:
+		if stmt.Expression != nil {
+			r.expression(s, stmt.Expression)
+		}
+	case *ast.ExpressionStatement:
+		r.expression(s, stmt.Expression)
+	}
+}
+
+func (r *resolver) expression(s *scope, x ast.Expression) {
+	switch x := x.(type) {
+	case *ast.Identifier:
+		r.use(s, x)
+	case *ast.InterpolatedString:
+		for _, e := range x.Parts {
+			r.expression(s, e)
+		}
+	case *ast.ArrayLiteral:
+		for _, e := range x.Elements {
+			r.expression(s, e)
+		}
+	case *ast.HashLiteral:
+		for _, e := range x.Pairs {
+			r.expression(s, e.Key)
+			r.expression(s, e.Value)
+		}
+	case *ast.FunctionLiteral:
+		r.deferred = append(r.deferred, func() {
+			fs := newScope(s)
+			for _, param := range x.Parameters {
+				r.declare(fs, paramSymbol, param)
+			}
+			r.statements(fs, x.Statements)
+		})
+	case *ast.PrefixExpression:
+		r.expression(s, x.Right)
+	case *ast.InfixExpression:
+		r.expression(s, x.Left)
+		r.expression(s, x.Right)
+	case *ast.LogicalExpression:
+		r.expression(s, x.Left)
+		r.expression(s, x.Right)
+	case *ast.If:
+		r.expression(s, x.Test)
+		r.statements(newScope(s), x.Body)
+		if x.Alt != nil {
+			r.expression(s, x.Alt)
+		}
+	case *ast.Else:
+		r.statements(newScope(s), x.Body)
+	case *ast.Call:
+		r.expression(s, x.Function)
+		for _, e := range x.Arguments {
+			r.expression(s, e)
+		}
+	case *ast.KeyAccess:
+		r.expression(s, x.Container)
+		r.expression(s, x.Key)
+	case *ast.Let:
+		r.expression(s, x.Right)
+		r.use(s, x.Left)
+	case *ast.KeyAssign:
+		r.expression(s, x.Left)
+		r.expression(s, x.Right)
+	}
+}
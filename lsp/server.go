@@ -0,0 +1,344 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/format"
+)
+
+// Server is a goore language server speaking JSON-RPC over a byte stream.
+type Server struct {
+	in       *bufio.Reader
+	out      io.Writer
+	docs     map[string]*document
+	shutdown bool
+}
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:   bufio.NewReader(in),
+		out:  out,
+		docs: map[string]*document{},
+	}
+}
+
+// Serve runs a server on in and out until the client sends exit or closes
+// the input.
+func Serve(in io.Reader, out io.Writer) error {
+	return NewServer(in, out).Run()
+}
+
+var errExit = errors.New("exit")
+
+func (s *Server) Run() error {
+	for {
+		body, err := readMessage(s.in)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := s.handle(body); err != nil {
+			if err == errExit && !s.shutdown {
+				return errors.New("exit before shutdown")
+			}
+			if err == errExit {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+type handler func(s *Server, params json.RawMessage) (any, error)
+
+var handlers map[string]handler
+
+func init() {
+	handlers = map[string]handler{
+		"initialize":                  (*Server).initialize,
+		"initialized":                 (*Server).ignore,
+		"shutdown":                    (*Server).shutdownServer,
+		"exit":                        (*Server).exit,
+		"textDocument/didOpen":        (*Server).didOpen,
+		"textDocument/didChange":      (*Server).didChange,
+		"textDocument/didClose":       (*Server).didClose,
+		"textDocument/definition":     (*Server).definition,
+		"textDocument/references":     (*Server).references,
+		"textDocument/hover":          (*Server).hover,
+		"textDocument/documentSymbol": (*Server).documentSymbol,
+		"textDocument/formatting":     (*Server).formatting,
+	}
+}
+
+func (s *Server) handle(body []byte) error {
+	var req request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return s.reply(nil, nil, &responseError{Code: codeParseError, Message: err.Error()})
+	}
+	fn, ok := handlers[req.Method]
+	if !ok {
+		if req.isNotification() {
+			return nil
+		}
+		return s.reply(req.ID, nil, &responseError{Code: codeMethodNotFound, Message: "method not found - " + req.Method})
+	}
+	result, err := fn(s, req.Params)
+	if err == errExit {
+		return err
+	}
+	if req.isNotification() {
+		// notifications have nobody to report bad parameters to
+		if _, ok := err.(*responseError); ok {
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		rerr, ok := err.(*responseError)
+		if !ok {
+			rerr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+		return s.reply(req.ID, nil, rerr)
+	}
+	return s.reply(req.ID, result, nil)
+}
+
+func (s *Server) reply(id json.RawMessage, result any, rerr *responseError) error {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	res := &response{JSONRPC: "2.0", ID: id, Error: rerr}
+	if rerr == nil {
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		res.Result = b
+	}
+	return writeMessage(s.out, res)
+}
+
+func (s *Server) notify(method string, params any) error {
+	return writeMessage(s.out, &notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func decode(params json.RawMessage, v any) error {
+	if err := json.Unmarshal(params, v); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: "unknown document - " + uri}
+	}
+	return d, nil
+}
+
+func (s *Server) initialize(json.RawMessage) (any, error) {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           syncIncremental,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			HoverProvider:              true,
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "goore"},
+	}, nil
+}
+
+func (s *Server) ignore(json.RawMessage) (any, error) {
+	return nil, nil
+}
+
+func (s *Server) shutdownServer(json.RawMessage) (any, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) exit(json.RawMessage) (any, error) {
+	return nil, errExit
+}
+
+func (s *Server) didOpen(params json.RawMessage) (any, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d := newDocument(p.TextDocument.URI, p.TextDocument.Version, p.TextDocument.Text)
+	s.docs[d.uri] = d
+	return nil, s.publishDiagnostics(d)
+}
+
+func (s *Server) didChange(params json.RawMessage) (any, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	d.version = p.TextDocument.Version
+	d.apply(p.ContentChanges)
+	return nil, s.publishDiagnostics(d)
+}
+
+func (s *Server) didClose(params json.RawMessage) (any, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	delete(s.docs, p.TextDocument.URI)
+	return nil, s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *Server) publishDiagnostics(d *document) error {
+	return s.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         d.uri,
+		Version:     d.version,
+		Diagnostics: d.diagnostics(),
+	})
+}
+
+// symbolAt returns the document and the symbol of the identifier at the
+// requested position, or a nil symbol when there is none.
+func (s *Server) symbolAt(p TextDocumentPositionParams) (*document, *ast.Identifier, *symbol, error) {
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	line, col := d.lineColumn(p.Position)
+	id, sym := d.index.at(line, col)
+	return d, id, sym, nil
+}
+
+func (s *Server) definition(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, _, sym, err := s.symbolAt(p)
+	if err != nil || sym == nil {
+		return nil, err
+	}
+	return &Location{URI: d.uri, Range: d.rangeOf(sym.name.Loc)}, nil
+}
+
+func (s *Server) references(params json.RawMessage) (any, error) {
+	var p ReferenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, _, sym, err := s.symbolAt(p.TextDocumentPositionParams)
+	if err != nil || sym == nil {
+		return nil, err
+	}
+	ids := sym.refs
+	if p.Context.IncludeDeclaration {
+		ids = append([]*ast.Identifier{sym.name}, ids...)
+	}
+	locs := make([]Location, len(ids))
+	for i, id := range ids {
+		locs[i] = Location{URI: d.uri, Range: d.rangeOf(id.Loc)}
+	}
+	sort.SliceStable(locs, func(i, j int) bool {
+		a, b := locs[i].Range.Start, locs[j].Range.Start
+		return a.Line < b.Line || a.Line == b.Line && a.Character < b.Character
+	})
+	return locs, nil
+}
+
+func (s *Server) hover(params json.RawMessage) (any, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, id, sym, err := s.symbolAt(p)
+	if err != nil || sym == nil {
+		return nil, err
+	}
+	kind := "def"
+	switch sym.kind {
+	case paramSymbol:
+		kind = "parameter"
+	case loopVarSymbol:
+		kind = "loop variable"
+	}
+	r := d.rangeOf(id.Loc)
+	return &Hover{
+		Contents: MarkupContent{
+			Kind:  "markdown",
+			Value: fmt.Sprintf("```goore\n%s %s\n```\ndefined at %s:%s", kind, sym.name.Name, d.fileName, sym.name.Loc),
+		},
+		Range: &r,
+	}, nil
+}
+
+func (s *Server) documentSymbol(params json.RawMessage) (any, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	syms := []DocumentSymbol{}
+	if d.prog == nil {
+		return syms, nil
+	}
+	for _, stmt := range d.prog.Statements {
+		def, ok := stmt.(*ast.Def)
+		if !ok {
+			continue
+		}
+		kind := SymbolVariable
+		if _, ok := def.Init.(*ast.FunctionLiteral); ok {
+			kind = SymbolFunction
+		}
+		syms = append(syms, DocumentSymbol{
+			Name:           def.Name.Name,
+			Detail:         "def",
+			Kind:           kind,
+			Range:          d.rangeOf(def.Loc),
+			SelectionRange: d.rangeOf(def.Name.Loc),
+		})
+	}
+	return syms, nil
+}
+
+func (s *Server) formatting(params json.RawMessage) (any, error) {
+	var p DocumentFormattingParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source([]byte(d.text), d.fileName)
+	if err != nil {
+		// nothing to offer while the document has syntax errors
+		return nil, nil
+	}
+	if string(src) == d.text {
+		return []TextEdit{}, nil
+	}
+	return []TextEdit{{Range: d.fullRange(), NewText: string(src)}}, nil
+}
@@ -0,0 +1,338 @@
+package lsp_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/lsp"
+)
+
+const uri = "file:///work/test.goore"
+
+const source = `def x = 1
+def add = -> (a, b) {
+	a + b + x
+}
+x += add(x, 2)
+`
+
+func frame(t *testing.T, msgs ...any) io.Reader {
+	var buf bytes.Buffer
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	}
+	return &buf
+}
+
+type message struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code int `json:"code"`
+	} `json:"error"`
+}
+
+func unframe(t *testing.T, out []byte) []message {
+	r := bufio.NewReader(bytes.NewReader(out))
+	msgs := []message{}
+	for {
+		header, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err == io.EOF {
+			return msgs
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		n, _ := strconv.Atoi(header.Get("Content-Length"))
+		body := make([]byte, n)
+		if _, err := io.ReadFull(r, body); err != nil {
+			t.Fatal(err)
+		}
+		var m message
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, m)
+	}
+}
+
+func call(id int, method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notify(method string, params any) map[string]any {
+	return map[string]any{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func position(line int, char int) map[string]any {
+	return map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+		"position":     lsp.Position{Line: line, Character: char},
+	}
+}
+
+// session runs a server over msgs, opening source first and shutting down
+// last, and returns the responses by request id and the published
+// diagnostics in order.
+func session(t *testing.T, msgs ...any) (map[int]message, [][]lsp.Diagnostic) {
+	all := []any{
+		call(0, "initialize", map[string]any{}),
+		notify("initialized", map[string]any{}),
+		notify("textDocument/didOpen", map[string]any{
+			"textDocument": lsp.TextDocumentItem{URI: uri, LanguageID: "goore", Version: 1, Text: source},
+		}),
+	}
+	all = append(all, msgs...)
+	all = append(all, call(-1, "shutdown", nil), notify("exit", nil))
+
+	var out bytes.Buffer
+	if err := lsp.Serve(frame(t, all...), &out); err != nil {
+		t.Fatal(err)
+	}
+	results := map[int]message{}
+	diags := [][]lsp.Diagnostic{}
+	for _, m := range unframe(t, out.Bytes()) {
+		if m.Method == "textDocument/publishDiagnostics" {
+			var p lsp.PublishDiagnosticsParams
+			if err := json.Unmarshal(m.Params, &p); err != nil {
+				t.Fatal(err)
+			}
+			diags = append(diags, p.Diagnostics)
+			continue
+		}
+		results[*m.ID] = m
+	}
+	return results, diags
+}
+
+func rangeOf(line int, beg int, end int) lsp.Range {
+	return lsp.Range{Start: lsp.Position{Line: line, Character: beg}, End: lsp.Position{Line: line, Character: end}}
+}
+
+func TestDefinition(t *testing.T) {
+	table := []struct {
+		name string
+		pos  map[string]any
+		want *lsp.Location
+	}{
+		{"global in function", position(2, 10), &lsp.Location{URI: uri, Range: rangeOf(0, 4, 5)}},
+		{"parameter", position(2, 1), &lsp.Location{URI: uri, Range: rangeOf(1, 14, 15)}},
+		{"end of identifier", position(4, 8), &lsp.Location{URI: uri, Range: rangeOf(1, 4, 7)}},
+		{"assignment target", position(4, 0), &lsp.Location{URI: uri, Range: rangeOf(0, 4, 5)}},
+		{"literal", position(4, 12), nil},
+	}
+
+	msgs := []any{}
+	for i, d := range table {
+		msgs = append(msgs, call(i+1, "textDocument/definition", d.pos))
+	}
+	results, _ := session(t, msgs...)
+	for i, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			var got *lsp.Location
+			if err := json.Unmarshal(results[i+1].Result, &got); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(d.want) {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+		})
+	}
+}
+
+func TestReferences(t *testing.T) {
+	params := position(0, 4)
+	params["context"] = map[string]any{"includeDeclaration": true}
+	results, _ := session(t, call(1, "textDocument/references", params))
+
+	var got []lsp.Location
+	if err := json.Unmarshal(results[1].Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []lsp.Range{rangeOf(0, 4, 5), rangeOf(2, 9, 10), rangeOf(4, 0, 1), rangeOf(4, 9, 10)}
+	if len(got) != len(want) {
+		t.Fatalf("want <%d> got <%d>", len(want), len(got))
+	}
+	for i, loc := range got {
+		if loc.Range != want[i] {
+			t.Errorf("want <%v> got <%v>", want[i], loc.Range)
+		}
+	}
+}
+
+func TestHover(t *testing.T) {
+	results, _ := session(t, call(1, "textDocument/hover", position(2, 5)))
+
+	var got lsp.Hover
+	if err := json.Unmarshal(results[1].Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := "```goore\nparameter b\n```\ndefined at /work/test.goore:(2:18):(2:18)"
+	if got.Contents.Value != want {
+		t.Errorf("want <%#v> got <%#v>", want, got.Contents.Value)
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	results, _ := session(t, call(1, "textDocument/documentSymbol", map[string]any{
+		"textDocument": map[string]any{"uri": uri},
+	}))
+
+	var got []lsp.DocumentSymbol
+	if err := json.Unmarshal(results[1].Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name string
+		kind lsp.SymbolKind
+	}{
+		{"x", lsp.SymbolVariable},
+		{"add", lsp.SymbolFunction},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want <%d> got <%d>", len(want), len(got))
+	}
+	for i, s := range got {
+		if s.Name != want[i].name || s.Kind != want[i].kind {
+			t.Errorf("want <%v %v> got <%v %v>", want[i].name, want[i].kind, s.Name, s.Kind)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	change := func(version int, changes ...lsp.TextDocumentContentChangeEvent) any {
+		return notify("textDocument/didChange", map[string]any{
+			"textDocument":   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: version},
+			"contentChanges": changes,
+		})
+	}
+	broken := rangeOf(4, 9, 10)
+	_, diags := session(t,
+		change(2, lsp.TextDocumentContentChangeEvent{Range: &broken, Text: "def"}),
+		change(3, lsp.TextDocumentContentChangeEvent{Text: "while x {\n"}),
+		change(4, lsp.TextDocumentContentChangeEvent{Text: source}),
+	)
+
+	want := []struct {
+		n       int
+		r       lsp.Range
+		message string
+	}{
+		{0, lsp.Range{}, ""},
+		{1, rangeOf(4, 9, 12), `unexpected token - "def"(Def)`},
+		{1, lsp.Range{Start: lsp.Position{Line: 0, Character: 8}, End: lsp.Position{Line: 1, Character: 0}}, "unclosed block"},
+		{0, lsp.Range{}, ""},
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("want <%d> got <%d>", len(want), len(diags))
+	}
+	for i, w := range want {
+		if len(diags[i]) != w.n {
+			t.Errorf("#%d: want <%d> got <%d>", i, w.n, len(diags[i]))
+			continue
+		}
+		if w.n == 0 {
+			continue
+		}
+		if diags[i][0].Range != w.r {
+			t.Errorf("#%d: want <%v> got <%v>", i, w.r, diags[i][0].Range)
+		}
+		if !strings.Contains(diags[i][0].Message, w.message) {
+			t.Errorf("#%d: want <%v> got <%v>", i, w.message, diags[i][0].Message)
+		}
+	}
+}
+
+func TestIncrementalChanges(t *testing.T) {
+	change := func(version int, changes ...lsp.TextDocumentContentChangeEvent) any {
+		return notify("textDocument/didChange", map[string]any{
+			"textDocument":   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: version},
+			"contentChanges": changes,
+		})
+	}
+	insert := rangeOf(1, 0, 0)
+	closing := rangeOf(1, 10, 10)
+	body := lsp.Range{Start: lsp.Position{Line: 2, Character: 21}, End: lsp.Position{Line: 4, Character: 0}}
+	last := rangeOf(6, 0, 0)
+	results, diags := session(t,
+		change(2, lsp.TextDocumentContentChangeEvent{Range: &insert, Text: "def y = \"a\n"}),
+		change(3,
+			lsp.TextDocumentContentChangeEvent{Range: &closing, Text: "\""},
+			lsp.TextDocumentContentChangeEvent{Range: &body, Text: "\n\ta * b\n"},
+			lsp.TextDocumentContentChangeEvent{Range: &last, Text: "y"},
+		),
+		call(1, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": uri}}),
+	)
+
+	if len(diags) != 3 || len(diags[1]) == 0 || len(diags[2]) != 0 {
+		t.Errorf("want diagnostics for the open string only got <%v>", diags)
+	}
+	var got []lsp.TextEdit
+	if err := json.Unmarshal(results[1].Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := "def x = 1\ndef y = \"a\"\ndef add = -> (a, b) {\n\ta * b\n}\nx += add(x, 2)\ny\n"
+	if len(got) != 1 || got[0].NewText != want {
+		t.Errorf("want <%#v> got <%v>", want, got)
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	results, _ := session(t,
+		notify("textDocument/didChange", map[string]any{
+			"textDocument":   lsp.VersionedTextDocumentIdentifier{URI: uri, Version: 2},
+			"contentChanges": []lsp.TextDocumentContentChangeEvent{{Text: "x=[1,2]"}},
+		}),
+		call(1, "textDocument/formatting", map[string]any{"textDocument": map[string]any{"uri": uri}}),
+	)
+
+	var got []lsp.TextEdit
+	if err := json.Unmarshal(results[1].Result, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []lsp.TextEdit{{Range: rangeOf(0, 0, 7), NewText: "x = [1, 2]\n"}}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("want <%v> got <%v>", want, got)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	results, _ := session(t, call(1, "textDocument/frobnicate", nil))
+	if results[1].Error == nil || results[1].Error.Code != -32601 {
+		t.Errorf("want method not found error got <%v>", results[1].Error)
+	}
+}
+
+func TestBadContentLength(t *testing.T) {
+	table := []struct {
+		length string
+		want   string
+	}{
+		{"x", "invalid Content-Length header"},
+		{"-1", "invalid Content-Length header"},
+		{"1000000000000", "message too large"},
+	}
+
+	for _, d := range table {
+		t.Run(d.length, func(t *testing.T) {
+			in := strings.NewReader("Content-Length: " + d.length + "\r\n\r\n{}")
+			err := lsp.Serve(in, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), d.want) {
+				t.Errorf("want <%s> got <%v>", d.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// Builtins returns the builtin functions in a fixed order, with puts and
+// print writing to out.
+func Builtins(out io.Writer) []*Builtin {
+	return []*Builtin{
+		{Name: "puts", Fn: func(args []Object) (Object, error) {
+			for _, a := range args {
+				fmt.Fprintln(out, a.String())
+			}
+			if len(args) == 0 {
+				fmt.Fprintln(out)
+			}
+			return Nil{}, nil
+		}},
+		{Name: "print", Fn: func(args []Object) (Object, error) {
+			for _, a := range args {
+				fmt.Fprint(out, a.String())
+			}
+			return Nil{}, nil
+		}},
+		{Name: "len", Fn: builtinLen},
+		{Name: "push", Fn: builtinPush},
+		{Name: "keys", Fn: builtinKeys},
+		{Name: "type", Fn: builtinType},
+		{Name: "range", Fn: builtinRange},
+	}
+}
+
+func checkArity(args []Object, n int) error {
+	if len(args) != n {
+		return fmt.Errorf("wrong number of arguments (given %d, expected %d)", len(args), n)
+	}
+	return nil
+}
+
+func builtinLen(args []Object) (Object, error) {
+	if err := checkArity(args, 1); err != nil {
+		return nil, err
+	}
+	switch a := args[0].(type) {
+	case String:
+		return Int(utf8.RuneCountInString(string(a))), nil
+	case *Array:
+		return Int(len(a.Elements)), nil
+	case *Hash:
+		return Int(a.Len()), nil
+	case Range:
+		return Int(a.Len()), nil
+	}
+	return nil, fmt.Errorf("unsupported argument - %s", args[0].Type())
+}
+
+func builtinPush(args []Object) (Object, error) {
+	if err := checkArity(args, 2); err != nil {
+		return nil, err
+	}
+	a, ok := args[0].(*Array)
+	if !ok {
+		return nil, fmt.Errorf("unsupported argument - %s", args[0].Type())
+	}
+	a.Elements = append(a.Elements, args[1])
+	return a, nil
+}
+
+func builtinKeys(args []Object) (Object, error) {
+	if err := checkArity(args, 1); err != nil {
+		return nil, err
+	}
+	h, ok := args[0].(*Hash)
+	if !ok {
+		return nil, fmt.Errorf("unsupported argument - %s", args[0].Type())
+	}
+	keys := make([]Object, 0, h.Len())
+	for _, p := range h.Pairs() {
+		keys = append(keys, p.Key)
+	}
+	return &Array{Elements: keys}, nil
+}
+
+func builtinType(args []Object) (Object, error) {
+	if err := checkArity(args, 1); err != nil {
+		return nil, err
+	}
+	return String(args[0].Type()), nil
+}
+
+// builtinRange makes a Range from its stop, its start and stop, or its
+// start, stop and step.
+func builtinRange(args []Object) (Object, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("wrong number of arguments (given %d, expected 1..3)", len(args))
+	}
+	bounds := make([]int, len(args))
+	for i, a := range args {
+		n, ok := a.(Int)
+		if !ok {
+			return nil, fmt.Errorf("unsupported argument - %s", a.Type())
+		}
+		bounds[i] = int(n)
+	}
+	switch len(bounds) {
+	case 1:
+		return Range{Stop: bounds[0], Step: 1}, nil
+	case 2:
+		return Range{Start: bounds[0], Stop: bounds[1], Step: 1}, nil
+	}
+	if bounds[2] == 0 {
+		return nil, fmt.Errorf("range step must not be zero")
+	}
+	return Range{Start: bounds[0], Stop: bounds[1], Step: bounds[2]}, nil
+}
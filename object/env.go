@@ -0,0 +1,36 @@
+package object
+
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+}
+
+func NewEnvironment(outer *Environment) *Environment {
+	return &Environment{
+		store: map[string]Object{},
+		outer: outer,
+	}
+}
+
+func (e *Environment) Define(name string, v Object) {
+	e.store[name] = v
+}
+
+func (e *Environment) Get(name string) (Object, bool) {
+	for env := e; env != nil; env = env.outer {
+		if v, ok := env.store[name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (e *Environment) Assign(name string, v Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			env.store[name] = v
+			return true
+		}
+	}
+	return false
+}
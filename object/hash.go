@@ -0,0 +1,96 @@
+package object
+
+import "strings"
+
+// HashKey identifies a Hashable object inside a Hash. Objects that are
+// Equal produce the same HashKey, so 1 and 1.0 address the same entry.
+type HashKey struct {
+	typ Type
+	i   int64
+	f   float64
+	s   string
+}
+
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
+func (Nil) HashKey() HashKey { return HashKey{typ: NilType} }
+
+func (b Bool) HashKey() HashKey {
+	k := HashKey{typ: BoolType}
+	if b {
+		k.i = 1
+	}
+	return k
+}
+
+func (i Int) HashKey() HashKey { return HashKey{typ: IntType, i: int64(i)} }
+
+func (f Float) HashKey() HashKey {
+	if isIntegral(float64(f)) {
+		return HashKey{typ: IntType, i: int64(f)}
+	}
+	return HashKey{typ: FloatType, f: float64(f)}
+}
+
+func (s String) HashKey() HashKey { return HashKey{typ: StringType, s: string(s)} }
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash is an insertion ordered map from Hashable objects.
+type Hash struct {
+	pairs map[HashKey]*HashPair
+	order []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{pairs: map[HashKey]*HashPair{}}
+}
+
+func (*Hash) Type() Type { return HashType }
+
+func (h *Hash) String() string { return h.Inspect() }
+
+func (h *Hash) Inspect() string {
+	pairs := make([]string, len(h.order))
+	for i, k := range h.order {
+		p := h.pairs[k]
+		pairs[i] = p.Key.Inspect() + ": " + p.Value.Inspect()
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (h *Hash) Len() int {
+	return len(h.order)
+}
+
+func (h *Hash) Get(k Hashable) (Object, bool) {
+	p, ok := h.pairs[k.HashKey()]
+	if !ok {
+		return nil, false
+	}
+	return p.Value, true
+}
+
+func (h *Hash) Set(k Hashable, v Object) {
+	hk := k.HashKey()
+	if p, ok := h.pairs[hk]; ok {
+		p.Value = v
+		return
+	}
+	h.pairs[hk] = &HashPair{Key: k, Value: v}
+	h.order = append(h.order, hk)
+}
+
+func (h *Hash) Pairs() []*HashPair {
+	pairs := make([]*HashPair, len(h.order))
+	for i, k := range h.order {
+		pairs[i] = h.pairs[k]
+	}
+	return pairs
+}
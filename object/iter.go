@@ -0,0 +1,113 @@
+package object
+
+import "fmt"
+
+// Iterable is an object a for loop goes through.
+type Iterable interface {
+	Object
+	// Iterate returns an iterator over the entries of the object. With
+	// pairs it yields their indexes or keys along with the values, and
+	// otherwise only what a loop with one variable takes: the values, or
+	// the keys of a hash.
+	Iterate(pairs bool) Iterator
+}
+
+// Iterator yields the entries of an Iterable one at a time. Next reports
+// false when there are no more; key is nil unless pairs were asked for.
+type Iterator interface {
+	Next() (key Object, value Object, ok bool)
+}
+
+// Range is the integers from Start up to Stop, excluding Stop, by Step.
+type Range struct {
+	Start int
+	Stop  int
+	Step  int
+}
+
+func (Range) Type() Type { return RangeType }
+
+func (r Range) String() string { return r.Inspect() }
+
+func (r Range) Inspect() string {
+	if r.Step == 1 {
+		return fmt.Sprintf("range(%d, %d)", r.Start, r.Stop)
+	}
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+func (r Range) Len() int {
+	switch {
+	case r.Step > 0 && r.Start < r.Stop:
+		return (r.Stop - r.Start + r.Step - 1) / r.Step
+	case r.Step < 0 && r.Start > r.Stop:
+		return (r.Start - r.Stop - r.Step - 1) / -r.Step
+	}
+	return 0
+}
+
+func (r Range) Iterate(pairs bool) Iterator {
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= r.Len() {
+			return nil, nil, false
+		}
+		i++
+		return indexKey(pairs, i-1), Int(r.Start + (i-1)*r.Step), true
+	})
+}
+
+// Iterate goes through the elements as they are at each step, so those
+// pushed meanwhile are seen too.
+func (a *Array) Iterate(pairs bool) Iterator {
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= len(a.Elements) {
+			return nil, nil, false
+		}
+		i++
+		return indexKey(pairs, i-1), a.Elements[i-1], true
+	})
+}
+
+// Iterate goes through the characters.
+func (s String) Iterate(pairs bool) Iterator {
+	runes := []rune(string(s))
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= len(runes) {
+			return nil, nil, false
+		}
+		i++
+		return indexKey(pairs, i-1), String(runes[i-1]), true
+	})
+}
+
+// Iterate goes through the entries in insertion order, including those
+// added meanwhile.
+func (h *Hash) Iterate(pairs bool) Iterator {
+	i := 0
+	return iteratorFunc(func() (Object, Object, bool) {
+		if i >= len(h.order) {
+			return nil, nil, false
+		}
+		p := h.pairs[h.order[i]]
+		i++
+		if !pairs {
+			return nil, p.Key, true
+		}
+		return p.Key, p.Value, true
+	})
+}
+
+type iteratorFunc func() (Object, Object, bool)
+
+func (f iteratorFunc) Next() (Object, Object, bool) { return f() }
+
+// indexKey is the key of the i-th element, if pairs were asked for.
+func indexKey(pairs bool, i int) Object {
+	if !pairs {
+		return nil
+	}
+	return Int(i)
+}
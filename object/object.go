@@ -0,0 +1,175 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/arikui1911/goore/ast"
+)
+
+type Type string
+
+const (
+	NilType      Type = "nil"
+	BoolType     Type = "bool"
+	IntType      Type = "int"
+	FloatType    Type = "float"
+	StringType   Type = "string"
+	ArrayType    Type = "array"
+	HashType     Type = "hash"
+	RangeType    Type = "range"
+	FunctionType Type = "function"
+	BuiltinType  Type = "builtin"
+)
+
+// Object is a runtime value. String returns the text puts prints and
+// Inspect returns the literal-like representation used inside containers.
+type Object interface {
+	Type() Type
+	String() string
+	Inspect() string
+}
+
+type Nil struct{}
+
+func (Nil) Type() Type        { return NilType }
+func (Nil) String() string    { return "nil" }
+func (n Nil) Inspect() string { return n.String() }
+
+type Bool bool
+
+func (Bool) Type() Type        { return BoolType }
+func (b Bool) String() string  { return strconv.FormatBool(bool(b)) }
+func (b Bool) Inspect() string { return b.String() }
+
+type Int int
+
+func (Int) Type() Type        { return IntType }
+func (i Int) String() string  { return strconv.Itoa(int(i)) }
+func (i Int) Inspect() string { return i.String() }
+
+type Float float64
+
+func (Float) Type() Type { return FloatType }
+
+func (f Float) String() string {
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
+
+func (f Float) Inspect() string { return f.String() }
+
+type String string
+
+func (String) Type() Type        { return StringType }
+func (s String) String() string  { return string(s) }
+func (s String) Inspect() string { return strconv.Quote(string(s)) }
+
+type Array struct {
+	Elements []Object
+}
+
+func (*Array) Type() Type { return ArrayType }
+
+func (a *Array) String() string { return a.Inspect() }
+
+func (a *Array) Inspect() string {
+	elems := make([]string, len(a.Elements))
+	for i, e := range a.Elements {
+		elems[i] = e.Inspect()
+	}
+	return "[" + strings.Join(elems, ", ") + "]"
+}
+
+type Function struct {
+	Parameters []*ast.Identifier
+	Body       []ast.Statement
+	Env        *Environment
+}
+
+func (*Function) Type() Type { return FunctionType }
+
+func (f *Function) String() string { return f.Inspect() }
+
+func (f *Function) Inspect() string {
+	return fmt.Sprintf("#<function:%p>", f)
+}
+
+type Builtin struct {
+	Name string
+	Fn   func(args []Object) (Object, error)
+}
+
+func (*Builtin) Type() Type { return BuiltinType }
+
+func (b *Builtin) String() string { return b.Inspect() }
+
+func (b *Builtin) Inspect() string {
+	return fmt.Sprintf("#<builtin:%s>", b.Name)
+}
+
+// Truthy reports whether o counts as true in a condition. Only nil and
+// false are falsy.
+func Truthy(o Object) bool {
+	switch o := o.(type) {
+	case Nil:
+		return false
+	case Bool:
+		return bool(o)
+	}
+	return true
+}
+
+func Equal(a Object, b Object) bool {
+	switch a := a.(type) {
+	case Int:
+		switch b := b.(type) {
+		case Int:
+			return a == b
+		case Float:
+			return Float(a) == b
+		}
+		return false
+	case Float:
+		switch b := b.(type) {
+		case Int:
+			return a == Float(b)
+		case Float:
+			return a == b
+		}
+		return false
+	case *Array:
+		b, ok := b.(*Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !Equal(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *Hash:
+		b, ok := b.(*Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, p := range a.Pairs() {
+			v, ok := b.Get(p.Key.(Hashable))
+			if !ok || !Equal(p.Value, v) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func isIntegral(f float64) bool {
+	return f == math.Trunc(f) && f >= math.MinInt64 && f < math.MaxInt64
+}
@@ -0,0 +1,126 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/arikui1911/goore/object"
+)
+
+func TestTruthy(t *testing.T) {
+	table := []struct {
+		name string
+		obj  object.Object
+		want bool
+	}{
+		{"nil", object.Nil{}, false},
+		{"false", object.Bool(false), false},
+		{"true", object.Bool(true), true},
+		{"zero", object.Int(0), true},
+		{"empty string", object.String(""), true},
+		{"empty array", &object.Array{}, true},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			if got := object.Truthy(d.obj); got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+		})
+	}
+}
+
+func TestInspect(t *testing.T) {
+	h := object.NewHash()
+	h.Set(object.String("b"), object.Int(1))
+	h.Set(object.Int(2), &object.Array{Elements: []object.Object{object.Nil{}, object.Float(2)}})
+	h.Set(object.String("b"), object.Bool(true))
+
+	table := []struct {
+		name    string
+		obj     object.Object
+		str     string
+		inspect string
+	}{
+		{"nil", object.Nil{}, "nil", "nil"},
+		{"int", object.Int(-3), "-3", "-3"},
+		{"float", object.Float(2), "2.0", "2.0"},
+		{"fraction", object.Float(0.25), "0.25", "0.25"},
+		{"string", object.String("a\"b"), "a\"b", `"a\"b"`},
+		{"array", &object.Array{Elements: []object.Object{object.String("x")}}, `["x"]`, `["x"]`},
+		{"hash", h, `{"b": true, 2: [nil, 2.0]}`, `{"b": true, 2: [nil, 2.0]}`},
+		{"range", object.Range{Start: 0, Stop: 3, Step: 1}, "range(0, 3)", "range(0, 3)"},
+		{"range with step", object.Range{Start: 5, Stop: 0, Step: -2}, "range(5, 0, -2)", "range(5, 0, -2)"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			if got := d.obj.String(); got != d.str {
+				t.Errorf("want <%s> got <%s>", d.str, got)
+			}
+			if got := d.obj.Inspect(); got != d.inspect {
+				t.Errorf("want <%s> got <%s>", d.inspect, got)
+			}
+		})
+	}
+}
+
+func TestHashKeys(t *testing.T) {
+	h := object.NewHash()
+	h.Set(object.Int(1), object.String("one"))
+	h.Set(object.String("1"), object.String("string one"))
+	h.Set(object.Nil{}, object.String("nil"))
+
+	table := []struct {
+		name string
+		key  object.Hashable
+		want string
+	}{
+		{"int", object.Int(1), "one"},
+		{"integral float", object.Float(1.0), "one"},
+		{"string", object.String("1"), "string one"},
+		{"nil", object.Nil{}, "nil"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			v, ok := h.Get(d.key)
+			if !ok {
+				t.Errorf("want <%s> got nothing", d.want)
+				return
+			}
+			if v.String() != d.want {
+				t.Errorf("want <%s> got <%s>", d.want, v)
+			}
+		})
+	}
+
+	if _, ok := h.Get(object.Float(1.5)); ok {
+		t.Errorf("want nothing for 1.5")
+	}
+	if h.Len() != 3 {
+		t.Errorf("want <3> got <%d>", h.Len())
+	}
+}
+
+func TestEqual(t *testing.T) {
+	table := []struct {
+		name string
+		a    object.Object
+		b    object.Object
+		want bool
+	}{
+		{"int and float", object.Int(1), object.Float(1), true},
+		{"int and string", object.Int(1), object.String("1"), false},
+		{"nil", object.Nil{}, object.Nil{}, true},
+		{"arrays", &object.Array{Elements: []object.Object{object.Int(1)}}, &object.Array{Elements: []object.Object{object.Float(1)}}, true},
+		{"different arrays", &object.Array{}, &object.Array{Elements: []object.Object{object.Nil{}}}, false},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			if got := object.Equal(d.a, d.b); got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package object
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/arikui1911/goore/ast"
+)
+
+func Prefix(op ast.Operation, right Object) (Object, error) {
+	switch op {
+	case ast.Not:
+		return Bool(!Truthy(right)), nil
+	case ast.Plus:
+		switch right.(type) {
+		case Int, Float:
+			return right, nil
+		}
+	case ast.Minus:
+		switch r := right.(type) {
+		case Int:
+			return -r, nil
+		case Float:
+			return -r, nil
+		}
+	}
+	return nil, fmt.Errorf("undefined operator %v for %s", op, right.Type())
+}
+
+func Infix(op ast.Operation, left Object, right Object) (Object, error) {
+	switch op {
+	case ast.Eq:
+		return Bool(Equal(left, right)), nil
+	case ast.Ne:
+		return Bool(!Equal(left, right)), nil
+	}
+
+	switch l := left.(type) {
+	case Int:
+		switch r := right.(type) {
+		case Int:
+			return intInfix(op, l, r)
+		case Float:
+			return floatInfix(op, Float(l), r)
+		}
+	case Float:
+		switch r := right.(type) {
+		case Int:
+			return floatInfix(op, l, Float(r))
+		case Float:
+			return floatInfix(op, l, r)
+		}
+	case String:
+		switch r := right.(type) {
+		case String:
+			return stringInfix(op, l, r)
+		case Int:
+			if op == ast.Mul && r >= 0 {
+				return String(strings.Repeat(string(l), int(r))), nil
+			}
+		}
+	case *Array:
+		if r, ok := right.(*Array); ok && op == ast.Add {
+			elems := make([]Object, 0, len(l.Elements)+len(r.Elements))
+			elems = append(elems, l.Elements...)
+			elems = append(elems, r.Elements...)
+			return &Array{Elements: elems}, nil
+		}
+	}
+	return nil, undefinedOperator(op, left, right)
+}
+
+func undefinedOperator(op ast.Operation, left Object, right Object) error {
+	return fmt.Errorf("undefined operator %v for %s and %s", op, left.Type(), right.Type())
+}
+
+func intInfix(op ast.Operation, l Int, r Int) (Object, error) {
+	switch op {
+	case ast.Add:
+		return l + r, nil
+	case ast.Sub:
+		return l - r, nil
+	case ast.Mul:
+		return l * r, nil
+	case ast.Div:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l / r, nil
+	case ast.Mod:
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return l % r, nil
+	case ast.Lt:
+		return Bool(l < r), nil
+	case ast.Le:
+		return Bool(l <= r), nil
+	case ast.Gt:
+		return Bool(l > r), nil
+	case ast.Ge:
+		return Bool(l >= r), nil
+	}
+	return nil, undefinedOperator(op, l, r)
+}
+
+func floatInfix(op ast.Operation, l Float, r Float) (Object, error) {
+	switch op {
+	case ast.Add:
+		return l + r, nil
+	case ast.Sub:
+		return l - r, nil
+	case ast.Mul:
+		return l * r, nil
+	case ast.Div:
+		return l / r, nil
+	case ast.Mod:
+		return Float(math.Mod(float64(l), float64(r))), nil
+	case ast.Lt:
+		return Bool(l < r), nil
+	case ast.Le:
+		return Bool(l <= r), nil
+	case ast.Gt:
+		return Bool(l > r), nil
+	case ast.Ge:
+		return Bool(l >= r), nil
+	}
+	return nil, undefinedOperator(op, l, r)
+}
+
+func stringInfix(op ast.Operation, l String, r String) (Object, error) {
+	switch op {
+	case ast.Add:
+		return l + r, nil
+	case ast.Lt:
+		return Bool(l < r), nil
+	case ast.Le:
+		return Bool(l <= r), nil
+	case ast.Gt:
+		return Bool(l > r), nil
+	case ast.Ge:
+		return Bool(l >= r), nil
+	}
+	return nil, undefinedOperator(op, l, r)
+}
+
+// Concat joins the objects as they print, for an interpolated string.
+func Concat(objs []Object) String {
+	var b strings.Builder
+	for _, o := range objs {
+		b.WriteString(o.String())
+	}
+	return String(b.String())
+}
+
+// Index returns c[k]. Missing hash keys and out of range indexes yield nil.
+func Index(c Object, k Object) (Object, error) {
+	switch c := c.(type) {
+	case *Array:
+		i, ok := k.(Int)
+		if !ok {
+			return nil, fmt.Errorf("array index must be int, not %s", k.Type())
+		}
+		if i < 0 || int(i) >= len(c.Elements) {
+			return Nil{}, nil
+		}
+		return c.Elements[i], nil
+	case *Hash:
+		hk, ok := k.(Hashable)
+		if !ok {
+			return nil, fmt.Errorf("unhashable key - %s", k.Type())
+		}
+		if v, ok := c.Get(hk); ok {
+			return v, nil
+		}
+		return Nil{}, nil
+	case String:
+		i, ok := k.(Int)
+		if !ok {
+			return nil, fmt.Errorf("string index must be int, not %s", k.Type())
+		}
+		rs := []rune(string(c))
+		if i < 0 || int(i) >= len(rs) {
+			return Nil{}, nil
+		}
+		return String(rs[i]), nil
+	}
+	return nil, fmt.Errorf("not indexable - %s", c.Type())
+}
+
+func SetIndex(c Object, k Object, v Object) error {
+	switch c := c.(type) {
+	case *Array:
+		i, ok := k.(Int)
+		if !ok {
+			return fmt.Errorf("array index must be int, not %s", k.Type())
+		}
+		if i < 0 || int(i) >= len(c.Elements) {
+			return fmt.Errorf("index out of range - %d", i)
+		}
+		c.Elements[i] = v
+		return nil
+	case *Hash:
+		hk, ok := k.(Hashable)
+		if !ok {
+			return fmt.Errorf("unhashable key - %s", k.Type())
+		}
+		c.Set(hk, v)
+		return nil
+	}
+	return fmt.Errorf("not assignable by key - %s", c.Type())
+}
@@ -0,0 +1,39 @@
+package parser
+
+// Version is a version of the language. Grammar changes that would break
+// older scripts come with a new version, so scripts keep their meaning by
+// asking for the version they were written in.
+type Version int
+
+const (
+	Version1 Version = 1 + iota
+
+	LatestVersion = Version1
+)
+
+// Config selects the language a Parser accepts. The zero Config is the
+// latest version with every feature on.
+type Config struct {
+	// Version is the language version; zero means LatestVersion.
+	Version Version
+	// NoTrailingCommas rejects a comma behind the last element of a list.
+	NoTrailingCommas bool
+	// RequireSemicolons makes a newline not enough to end a statement,
+	// except behind a block or before the '}' closing one.
+	RequireSemicolons bool
+	// Strict rejects an if expression whose value is used, such as the
+	// right side of an assignment, taking it only as a statement.
+	Strict bool
+}
+
+// WithConfig makes the parser accept the language c selects.
+func WithConfig(c Config) Option {
+	return func(p *Parser) { p.config = c }
+}
+
+func (c Config) version() Version {
+	if c.Version == 0 {
+		return LatestVersion
+	}
+	return c.Version
+}
@@ -1,10 +1,11 @@
 package parser
 
 import (
-	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/diag"
 	"github.com/arikui1911/goore/token"
 )
 
@@ -12,6 +13,8 @@ type precedence int
 
 const (
 	lowestPrecedence precedence = iota
+	orPrecedence
+	andPrecedence
 	equalityPrecedence
 	comparePrecedence
 	additivePrecedence
@@ -38,6 +41,8 @@ func init() {
 		token.IntLiteral:    parseIntLiteral,
 		token.FloatLiteral:  parseFloatLiteral,
 		token.StringLiteral: parseStringLiteral,
+		token.Invalid:       parseInvalid,
+		token.StringBegin:   parseInterpolatedString,
 		token.Add:           parsePrefixed,
 		token.Sub:           parsePrefixed,
 		token.Bang:          parsePrefixed,
@@ -48,6 +53,8 @@ func init() {
 		token.If:            parseIf,
 	}
 	infixedParsers = map[token.TokenTag]infixedParser{
+		token.Or:          parseLogical,
+		token.And:         parseLogical,
 		token.Eq:          parseInfixed,
 		token.Ne:          parseInfixed,
 		token.Ge:          parseInfixed,
@@ -66,10 +73,13 @@ func init() {
 		token.LetSub:      parseLet,
 		token.LetMul:      parseLet,
 		token.LetDiv:      parseLet,
+		token.LetMod:      parseLet,
 	}
 }
 
 var precedences = map[token.TokenTag]precedence{
+	token.Or:          orPrecedence,
+	token.And:         andPrecedence,
 	token.Eq:          equalityPrecedence,
 	token.Ne:          equalityPrecedence,
 	token.Ge:          comparePrecedence,
@@ -98,7 +108,17 @@ func parseExpression(p *Parser, prec precedence) (ast.Expression, error) {
 	}
 	fn, ok := prefixedParsers[t.Tag]
 	if !ok {
-		return nil, p.unexpected(t, "for beginning of expression")
+		d := p.unexpected(t, "for beginning of expression")
+		if statementKeywords[t.Tag] {
+			return nil, d
+		}
+		// the token ending the expression is left to the caller
+		if !closers[t.Tag] {
+			if _, err := p.nextToken(); err != nil {
+				return nil, err
+			}
+		}
+		return p.invalidExpression(d), nil
 	}
 	left, err := fn(p)
 	if err != nil {
@@ -128,6 +148,20 @@ func parseExpression(p *Parser, prec precedence) (ast.Expression, error) {
 	return left, nil
 }
 
+// closers are the tokens that may end an expression.
+var closers = map[token.TokenTag]bool{
+	token.Comma:        true,
+	token.Colon:        true,
+	token.RightParen:   true,
+	token.RightBracket: true,
+	token.RightBrace:   true,
+	token.Newline:      true,
+	token.Semicolon:    true,
+	token.StringMiddle: true,
+	token.StringEnd:    true,
+	token.EOF:          true,
+}
+
 func parseIdentifier(p *Parser) (ast.Expression, error) {
 	t, err := p.nextToken()
 	if err != nil {
@@ -160,11 +194,11 @@ func parseIntLiteral(p *Parser) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
-	i64, err := strconv.ParseInt(t.Value, 10, 64)
+	i64, err := strconv.ParseInt(strings.ReplaceAll(t.Value, "_", ""), 0, 64)
 	if err != nil {
-		return nil, fmt.Errorf("%s:%s: %w", p.fileName, t.Location, err)
+		return p.invalidExpression(p.errorf(&t.Location, diag.InvalidNumber, "integer literal out of range - %s", t.Value)), nil
 	}
-	return &ast.IntLiteral{Loc: &t.Location, Value: int(i64)}, nil
+	return &ast.IntLiteral{Loc: &t.Location, Value: int(i64), Raw: t.Value}, nil
 }
 
 func parseFloatLiteral(p *Parser) (ast.Expression, error) {
@@ -172,11 +206,22 @@ func parseFloatLiteral(p *Parser) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
-	f64, err := strconv.ParseFloat(t.Value, 64)
+	f64, err := strconv.ParseFloat(strings.ReplaceAll(t.Value, "_", ""), 64)
+	if err != nil {
+		return p.invalidExpression(p.errorf(&t.Location, diag.InvalidNumber, "float literal out of range - %s", t.Value)), nil
+	}
+	return &ast.FloatLiteral{Loc: &t.Location, Value: f64, Raw: t.Value}, nil
+}
+
+// parseInvalid stands in for a malformed literal, which the lexer has
+// reported already.
+func parseInvalid(p *Parser) (ast.Expression, error) {
+	t, err := p.nextToken()
 	if err != nil {
-		return nil, fmt.Errorf("%s:%s: %w", p.fileName, t.Location, err)
+		return nil, err
 	}
-	return &ast.FloatLiteral{Loc: &t.Location, Value: f64}, nil
+	p.broken = true
+	return &ast.InvalidExpression{Loc: &t.Location, Err: p.lexError}, nil
 }
 
 func parseStringLiteral(p *Parser) (ast.Expression, error) {
@@ -187,6 +232,69 @@ func parseStringLiteral(p *Parser) (ast.Expression, error) {
 	return &ast.StringLiteral{Loc: &t.Location, Value: t.Value}, nil
 }
 
+// parseInterpolatedString parses the tokens of a string from its
+// StringBegin to its StringEnd, which hold the text around the embedded
+// expressions.
+func parseInterpolatedString(p *Parser) (ast.Expression, error) {
+	begin, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	base := p.nesting
+	x := &ast.InterpolatedString{}
+	t := begin
+	for {
+		if t.Value != "" {
+			x.Parts = append(x.Parts, &ast.StringLiteral{Loc: &t.Location, Value: t.Value})
+		}
+		if t.Tag == token.StringEnd {
+			break
+		}
+		e, err := parseExpression(p, lowestPrecedence)
+		if err != nil {
+			return nil, err
+		}
+		x.Parts = append(x.Parts, e)
+		t, err = p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Tag == token.StringMiddle || t.Tag == token.StringEnd {
+			continue
+		}
+		d := p.unexpected(t, "expect '}' to close the interpolation")
+		if t, err = skipInterpolation(p, t, base); err != nil {
+			return nil, err
+		}
+		if t.Tag == token.EOF {
+			return nil, d
+		}
+		p.addError(d)
+		p.broken = true
+	}
+	x.Loc = setLocation(nil, &begin.Location, &t.Location)
+	return x, nil
+}
+
+// skipInterpolation skips from t to the end of the interpolation that
+// left nesting at base, or to the end of file.
+func skipInterpolation(p *Parser, t token.Token, base int) (token.Token, error) {
+	for {
+		switch {
+		case t.Tag == token.EOF:
+			return t, nil
+		case t.Tag == token.StringMiddle && p.nesting == base:
+			return t, nil
+		case t.Tag == token.StringEnd && p.nesting == base-1:
+			return t, nil
+		}
+		var err error
+		if t, err = p.nextToken(); err != nil {
+			return token.Token{}, err
+		}
+	}
+}
+
 var prefixOperators = map[token.TokenTag]ast.Operation{
 	token.Add:  ast.Plus,
 	token.Sub:  ast.Minus,
@@ -198,15 +306,21 @@ func parsePrefixed(p *Parser) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs, err := p.takeCommentsBehind()
+	if err != nil {
+		return nil, err
+	}
 	x, err := parseExpression(p, prefixPrecedence)
 	if err != nil {
 		return nil, err
 	}
-	return &ast.PrefixExpression{
+	n := &ast.PrefixExpression{
 		Loc:      setLocation(nil, &t.Location, x.Location()),
 		Operator: prefixOperators[t.Tag],
 		Right:    x,
-	}, nil
+	}
+	p.comments.AddInner(n, cs...)
+	return n, nil
 }
 
 func parseParenExpr(p *Parser) (ast.Expression, error) {
@@ -237,10 +351,12 @@ func parseArrayLiteral(p *Parser) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.ArrayLiteral{
+	x := &ast.ArrayLiteral{
 		Loc:      setLocation(nil, &lb.Location, &rb.Location),
 		Elements: elems,
-	}, nil
+	}
+	p.comments.AddInner(x, p.takeComments()...)
+	return x, nil
 }
 
 func parseArrayElement(p *Parser) (ast.Expression, error) {
@@ -256,10 +372,12 @@ func parseHashLiteral(p *Parser) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &ast.HashLiteral{
+	x := &ast.HashLiteral{
 		Loc:   setLocation(nil, &lb.Location, &rb.Location),
 		Pairs: pairs,
-	}, nil
+	}
+	p.comments.AddInner(x, p.takeComments()...)
+	return x, nil
 }
 
 func parseHashEntry(p *Parser) (*ast.HashEntry, error) {
@@ -271,18 +389,37 @@ func parseHashEntry(p *Parser) (*ast.HashEntry, error) {
 	if err != nil {
 		return nil, err
 	}
-	if t.Tag != token.Colon {
-		return nil, p.unexpected(t, "expect colon to delimitting key and value")
+	var v ast.Expression
+	var cs []*ast.Comment
+	if t.Tag == token.Colon {
+		cs, err = p.takeCommentsBehind()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// take the token for the value if it can begin one
+		d := p.unexpected(t, "expect colon to delimitting key and value")
+		p.pushBack(t)
+		if _, ok := prefixedParsers[t.Tag]; !ok {
+			v = p.invalidExpression(d)
+		} else {
+			p.addError(d)
+			p.broken = true
+		}
 	}
-	v, err := parseExpression(p, lowestPrecedence)
-	if err != nil {
-		return nil, err
+	if v == nil {
+		v, err = parseExpression(p, lowestPrecedence)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return &ast.HashEntry{
+	e := &ast.HashEntry{
 		Loc:   setLocation(nil, k.Location(), v.Location()),
 		Key:   k,
 		Value: v,
-	}, nil
+	}
+	p.comments.AddInner(e, cs...)
+	return e, nil
 }
 
 func parseFunctionLiteral(p *Parser) (ast.Expression, error) {
@@ -306,16 +443,22 @@ func parseFunctionLiteral(p *Parser) (ast.Expression, error) {
 		params = []*ast.Identifier{}
 	}
 
+	// the loops around the literal are not the body's
+	loops := p.loops
+	p.loops = 0
 	stmts, rb, err := parseBlock(p)
+	p.loops = loops
 	if err != nil {
 		return nil, err
 	}
 
-	return &ast.FunctionLiteral{
+	x := &ast.FunctionLiteral{
 		Loc:        setLocation(nil, &arrow.Location, &rb.Location),
 		Parameters: params,
 		Statements: stmts,
-	}, nil
+	}
+	p.comments.AddInner(x, p.takeComments()...)
+	return x, nil
 }
 
 func parseParameter(p *Parser) (*ast.Identifier, error) {
@@ -334,6 +477,12 @@ func parseIf(p *Parser) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
+	if kw.Tag == token.If {
+		if p.config.Strict && !p.ifStatement {
+			p.addError(ifAsValue(p, kw))
+		}
+		p.ifStatement = false
+	}
 	switch kw.Tag {
 	case token.If, token.Elsif:
 		test, err := parseExpression(p, lowestPrecedence)
@@ -344,6 +493,7 @@ func parseIf(p *Parser) (ast.Expression, error) {
 		if err != nil {
 			return nil, err
 		}
+		inner := p.takeComments()
 		alt, err := parseIf(p)
 		if err != nil {
 			return nil, err
@@ -352,19 +502,29 @@ func parseIf(p *Parser) (ast.Expression, error) {
 		if alt != nil {
 			loc = setLocation(loc, nil, alt.Location())
 		}
-		return &ast.If{Loc: loc, Test: test, Body: body, Alt: alt}, nil
+		x := &ast.If{Loc: loc, Test: test, Body: body, Alt: alt}
+		p.comments.AddInner(x, inner...)
+		return x, nil
 	case token.Else:
 		body, rb, err := parseBlock(p)
 		if err != nil {
 			return nil, err
 		}
-		return &ast.Else{Loc: setLocation(nil, &kw.Location, &rb.Location), Body: body}, nil
+		x := &ast.Else{Loc: setLocation(nil, &kw.Location, &rb.Location), Body: body}
+		p.comments.AddInner(x, p.takeComments()...)
+		return x, nil
 	default:
 		p.pushBack(kw)
 		return nil, nil
 	}
 }
 
+// ifAsValue reports the if expression beginning at kw being used as a
+// value in strict mode.
+func ifAsValue(p *Parser, kw token.Token) *diag.Diagnostic {
+	return p.errorf(&kw.Location, diag.IfAsValue, "if expression used as a value in strict mode")
+}
+
 var infixOperators = map[token.TokenTag]ast.Operation{
 	token.Eq:  ast.Eq,
 	token.Ne:  ast.Ne,
@@ -384,16 +544,50 @@ func parseInfixed(p *Parser, left ast.Expression) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs, err := p.takeCommentsBehind()
+	if err != nil {
+		return nil, err
+	}
 	right, err := parseExpression(p, precedences[t.Tag])
 	if err != nil {
 		return nil, err
 	}
-	return &ast.InfixExpression{
+	x := &ast.InfixExpression{
 		Loc:      setLocation(nil, left.Location(), right.Location()),
 		Operator: infixOperators[t.Tag],
 		Left:     left,
 		Right:    right,
-	}, nil
+	}
+	p.comments.AddInner(x, cs...)
+	return x, nil
+}
+
+var logicalOperators = map[token.TokenTag]ast.Operation{
+	token.And: ast.And,
+	token.Or:  ast.Or,
+}
+
+func parseLogical(p *Parser, left ast.Expression) (ast.Expression, error) {
+	t, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	cs, err := p.takeCommentsBehind()
+	if err != nil {
+		return nil, err
+	}
+	right, err := parseExpression(p, precedences[t.Tag])
+	if err != nil {
+		return nil, err
+	}
+	x := &ast.LogicalExpression{
+		Loc:      setLocation(nil, left.Location(), right.Location()),
+		Operator: logicalOperators[t.Tag],
+		Left:     left,
+		Right:    right,
+	}
+	p.comments.AddInner(x, cs...)
+	return x, nil
 }
 
 func parseCall(p *Parser, fn ast.Expression) (ast.Expression, error) {
@@ -402,14 +596,25 @@ func parseCall(p *Parser, fn ast.Expression) (ast.Expression, error) {
 		return nil, err
 	}
 	args, rp, err := parseCommaList(p, token.RightParen, parseArgument)
+	if d, ok := err.(*diag.Diagnostic); ok {
+		// keep the arguments read so far
+		x := p.invalidExpression(d)
+		return &ast.Call{
+			Loc:       setLocation(nil, fn.Location(), x.Loc),
+			Function:  fn,
+			Arguments: append(args, x),
+		}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &ast.Call{
+	x := &ast.Call{
 		Loc:       setLocation(nil, fn.Location(), &rp.Location),
 		Function:  fn,
 		Arguments: args,
-	}, nil
+	}
+	p.comments.AddInner(x, p.takeComments()...)
+	return x, nil
 }
 
 func parseArgument(p *Parser) (ast.Expression, error) {
@@ -421,6 +626,10 @@ func parseKeyAccess(p *Parser, c ast.Expression) (ast.Expression, error) {
 	if err != nil {
 		return nil, err
 	}
+	cs, err := p.takeCommentsBehind()
+	if err != nil {
+		return nil, err
+	}
 	k, err := parseExpression(p, lowestPrecedence)
 	if err != nil {
 		return nil, err
@@ -431,20 +640,26 @@ func parseKeyAccess(p *Parser, c ast.Expression) (ast.Expression, error) {
 		return nil, err
 	}
 	if t.Tag == token.Comma || t.Tag == token.Newline {
+		sep := t
 		t, err = p.nextToken()
 		if err != nil {
 			return nil, err
 		}
+		if sep.Tag == token.Comma && t.Tag == token.RightBracket {
+			checkTrailingComma(p, sep)
+		}
 	}
 	if t.Tag != token.RightBracket {
 		return nil, p.unexpected(t, "expect right bracket")
 	}
 
-	return &ast.KeyAccess{
+	x := &ast.KeyAccess{
 		Loc:       setLocation(nil, c.Location(), &t.Location),
 		Container: c,
 		Key:       k,
-	}, nil
+	}
+	p.comments.AddInner(x, cs...)
+	return x, nil
 }
 
 var selfLetOperators = map[token.TokenTag]ast.Operation{
@@ -463,13 +678,17 @@ func parseLet(p *Parser, left ast.Expression) (ast.Expression, error) {
 	case *ast.KeyAccess:
 		isLet = false
 	default:
-		return nil, fmt.Errorf("%s:%s: invalid let left part", p.fileName, left.Location())
+		return nil, p.errorf(left.Location(), diag.InvalidAssignment, "invalid let left part")
 	}
 
 	let, err := p.nextToken()
 	if err != nil {
 		return nil, err
 	}
+	cs, err := p.takeCommentsBehind()
+	if err != nil {
+		return nil, err
+	}
 
 	right, err := parseExpression(p, highestPrecedence)
 	if err != nil {
@@ -485,22 +704,27 @@ func parseLet(p *Parser, left ast.Expression) (ast.Expression, error) {
 		}
 	}
 
+	var x ast.Expression
 	if isLet {
-		return &ast.Let{
+		x = &ast.Let{
 			Loc:   setLocation(nil, left.Location(), right.Location()),
 			Left:  left.(*ast.Identifier),
 			Right: right,
-		}, nil
+		}
+	} else {
+		x = &ast.KeyAssign{
+			Loc:   setLocation(nil, left.Location(), right.Location()),
+			Left:  left.(*ast.KeyAccess),
+			Right: right,
+		}
 	}
-
-	return &ast.KeyAssign{
-		Loc:   setLocation(nil, left.Location(), right.Location()),
-		Left:  left.(*ast.KeyAccess),
-		Right: right,
-	}, nil
+	p.comments.AddInner(x, cs...)
+	return x, nil
 }
 
 func parseCommaList[T ast.Expression](p *Parser, term token.TokenTag, elementParser func(*Parser) (T, error)) ([]T, token.Token, error) {
+	base := p.nesting
+
 	// empty?
 	t, err := p.nextToken()
 	if err != nil {
@@ -512,16 +736,22 @@ func parseCommaList[T ast.Expression](p *Parser, term token.TokenTag, elementPar
 	p.pushBack(t)
 
 	// first element
-	e, err := elementParser(p)
+	leading := p.takeComments()
+	e, err := parseElement(p, base, elementParser)
 	if err != nil {
 		return nil, token.Token{}, err
 	}
+	p.comments.AddLeading(e, leading...)
 	list := []T{e}
+	done := func(t token.Token) ([]T, token.Token, error) {
+		p.comments.AddTrailing(list[len(list)-1], p.takeComments()...)
+		return list, t, nil
+	}
 
 	for {
 		t, err := p.nextToken()
 		if err != nil {
-			return nil, token.Token{}, err
+			return list, token.Token{}, err
 		}
 
 		switch t.Tag {
@@ -529,39 +759,115 @@ func parseCommaList[T ast.Expression](p *Parser, term token.TokenTag, elementPar
 			// auto newline and term
 			nt, err := p.nextToken()
 			if err != nil {
-				return nil, token.Token{}, err
+				return list, token.Token{}, err
 			}
 			if nt.Tag == term {
-				return list, nt, nil
+				return done(nt)
+			}
+			if closers[nt.Tag] {
+				return list, token.Token{}, p.unexpected(nt, "to close the list")
 			}
 
 			// カンマが欠けていたので改行トークンが入ってしまったとして、次の要素へ
 			p.pushBack(nt)
-			p.addError(fmt.Errorf("%s:%s: missing comma for delimiter", p.fileName, nt.Location))
+			d := missingComma(p, list[len(list)-1], nt)
+			if p.strictCommas {
+				return list, token.Token{}, d
+			}
+			p.addError(d)
 		case term:
 			// term without auto newline (e.g. [123])
-			return list, t, nil
+			return done(t)
 		case token.Comma:
 			// consume last extra comma
+			comma := t
 			t, err := p.nextToken()
 			if err != nil {
-				return nil, token.Token{}, err
+				return list, token.Token{}, err
 			}
 			if t.Tag == term {
-				return list, t, nil
+				checkTrailingComma(p, comma)
+				return done(t)
 			}
 			p.pushBack(t)
 		default:
+			if closers[t.Tag] {
+				return list, token.Token{}, p.unexpected(t, "to close the list")
+			}
 			// カンマが欠けてると仮定して、次の要素を読みにいく
 			p.pushBack(t)
-			p.addError(fmt.Errorf("%s:%s: missing comma for delimiter", p.fileName, t.Location))
+			d := missingComma(p, list[len(list)-1], t)
+			if p.strictCommas {
+				return list, token.Token{}, d
+			}
+			p.addError(d)
 		}
 
 		// next element
-		e, err := elementParser(p)
+		p.attachTrailing(list[len(list)-1])
+		leading := p.takeComments()
+		e, err := parseElement(p, base, elementParser)
 		if err != nil {
-			return nil, token.Token{}, err
+			return list, token.Token{}, err
 		}
+		p.comments.AddLeading(e, leading...)
 		list = append(list, e)
 	}
 }
+
+// parseElement parses an element of a list inside base open brackets. An
+// expression element failing to parse is reported and replaced by an
+// InvalidExpression up to the comma or term behind it.
+func parseElement[T ast.Expression](p *Parser, base int, elementParser func(*Parser) (T, error)) (T, error) {
+	e, err := elementParser(p)
+	if err == nil {
+		return e, nil
+	}
+	d, ok := err.(*diag.Diagnostic)
+	x := &ast.InvalidExpression{Err: err}
+	inv, fits := any(x).(T)
+	if !ok || !fits {
+		return e, err
+	}
+	loc := d.Loc
+	x.Loc = &loc
+	for {
+		t, err := p.nextToken()
+		if err != nil {
+			return e, err
+		}
+		if t.Tag == token.EOF {
+			p.pushBack(t)
+			return e, d
+		}
+		if (p.nesting == base && (t.Tag == token.Comma || t.Tag == token.Newline)) ||
+			(p.nesting < base && closers[t.Tag]) {
+			// the end of the element, or a bracket closing the list
+			p.pushBack(t)
+			break
+		}
+		setLocation(x.Loc, nil, &t.Location)
+	}
+	p.addError(d)
+	p.broken = true
+	return inv, nil
+}
+
+// checkTrailingComma reports comma behind the last element of a list if
+// the language has no trailing commas.
+func checkTrailingComma(p *Parser, comma token.Token) {
+	if !p.config.NoTrailingCommas {
+		return
+	}
+	d := p.errorf(&comma.Location, diag.TrailingComma, "trailing comma is not allowed")
+	d.Fix = &diag.Fix{Message: "remove comma", Loc: comma.Location}
+	p.addError(d)
+}
+
+// missingComma reports the element at t lacking a comma after the previous
+// element prev.
+func missingComma(p *Parser, prev ast.Node, t token.Token) *diag.Diagnostic {
+	d := p.errorf(&t.Location, diag.MissingComma, "missing comma for delimiter")
+	d.Fix = &diag.Fix{Message: "insert comma", Loc: *prev.Location(), Insert: true, NewText: ","}
+	return d
+}
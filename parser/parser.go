@@ -6,35 +6,101 @@ import (
 	"strings"
 
 	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/diag"
 	"github.com/arikui1911/goore/lexer"
 	"github.com/arikui1911/goore/token"
 )
 
-func ParseReader(src io.Reader, fileName string) (*ast.Program, error) {
-	return New(lexer.New(src), fileName).Parse()
+func ParseReader(src io.Reader, fileName string, opts ...Option) (*ast.Program, error) {
+	return New(lexer.New(src), fileName, opts...).Parse()
 }
 
-func ParseString(src string, fileName string) (*ast.Program, error) {
-	return ParseReader(strings.NewReader(src), fileName)
+// ParseString parses src as a file of its own set.
+func ParseString(src string, fileName string, opts ...Option) (*ast.Program, error) {
+	return ParseFile(token.NewFileSet(), fileName, src, opts...)
+}
+
+// ParseFile adds src to fset and parses it; the Program refers to the
+// added file.
+func ParseFile(fset *token.FileSet, fileName string, src string, opts ...Option) (*ast.Program, error) {
+	p := New(lexer.New(strings.NewReader(src)), fileName, opts...)
+	p.file = fset.AddFile(fileName, src)
+	return p.Parse()
 }
 
 type Parser struct {
 	lexer         *lexer.Lexer
 	fileName      string
+	file          *token.File
 	savedToken    token.Token
 	hasSavedToken bool
-	errs          []error
+	diags         diag.List
+	comments      ast.CommentMap
+	pending       []*ast.Comment
+	// braces counts the braces lexed so far that are still open; blocks
+	// holds that count right inside each enclosing block.
+	braces int
+	blocks []int
+	// nesting counts the brackets of any kind still open in the same way.
+	nesting int
+	// loops counts the loops around the statement being parsed inside the
+	// innermost function, which break and continue cannot leave.
+	loops int
+	// broken tells that an expression of the current statement has an
+	// InvalidExpression, whose error is reported already.
+	broken bool
+	// lexError is the last diagnostic of the lexer, which is about the
+	// token behind it if that is Invalid.
+	lexError *diag.Diagnostic
+
+	config       Config
+	errorLimit   int
+	strictCommas bool
+	// ifStatement tells that the next if expression is a statement by
+	// itself, which Config.Strict asks for.
+	ifStatement bool
+	// stopped is where the parser reached its error limit; from there on
+	// it reads an end of file.
+	stopped *token.Location
+}
+
+// Option configures a Parser.
+type Option func(*Parser)
+
+// ErrorLimit makes the parser stop at the n-th error, keeping the
+// statements parsed before it. Zero or less means no limit, which is the
+// default.
+func ErrorLimit(n int) Option {
+	return func(p *Parser) { p.errorLimit = n }
+}
+
+// FailFast makes the parser stop at the first error.
+func FailFast() Option {
+	return ErrorLimit(1)
 }
 
-func New(l *lexer.Lexer, fileName string) *Parser {
-	return &Parser{
+// StrictCommas makes a missing comma in a list an error the list cannot
+// go on behind, instead of assuming the comma.
+func StrictCommas() Option {
+	return func(p *Parser) { p.strictCommas = true }
+}
+
+func New(l *lexer.Lexer, fileName string, opts ...Option) *Parser {
+	p := &Parser{
 		lexer:    l,
 		fileName: fileName,
-		errs:     []error{},
+		comments: ast.CommentMap{},
 	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
 }
 
 func (p *Parser) Parse() (*ast.Program, error) {
+	if v := p.config.version(); v < Version1 || v > LatestVersion {
+		return nil, fmt.Errorf("%s: unsupported language version %d", p.fileName, v)
+	}
 	return parseProgram(p)
 }
 
@@ -43,11 +109,40 @@ func (p *Parser) nextToken() (token.Token, error) {
 		p.hasSavedToken = false
 		return p.savedToken, nil
 	}
-	t, err := p.lexer.NextToken()
-	if err != nil {
-		return token.Token{}, fmt.Errorf("%s:%w", p.fileName, err)
+	if p.stopped != nil {
+		return token.Token{Tag: token.EOF, Location: *p.stopped}, nil
+	}
+	for {
+		t, err := p.lexer.NextToken()
+		if d, ok := err.(*diag.Diagnostic); ok {
+			// the lexer goes on behind a bad character, string or number
+			d.FileName = p.fileName
+			p.addError(d)
+			p.lexError = d
+			continue
+		}
+		if err != nil {
+			return token.Token{}, fmt.Errorf("%s:%w", p.fileName, err)
+		}
+		switch t.Tag {
+		case token.LeftBrace:
+			p.braces++
+		case token.RightBrace:
+			// a stray brace at top level closes nothing
+			p.braces = max(p.braces-1, 0)
+		}
+		switch t.Tag {
+		case token.LeftParen, token.LeftBracket, token.LeftBrace, token.StringBegin:
+			p.nesting++
+		case token.RightParen, token.RightBracket, token.RightBrace, token.StringEnd:
+			p.nesting = max(p.nesting-1, 0)
+		}
+		if t.Tag != token.Comment {
+			return t, nil
+		}
+		// comments wait here until a node to attach them to is parsed
+		p.pending = append(p.pending, &ast.Comment{Loc: &t.Location, Text: t.Value})
 	}
-	return t, nil
 }
 
 func (p *Parser) pushBack(t token.Token) {
@@ -64,12 +159,72 @@ func (p *Parser) peekToken() (token.Token, error) {
 	return t, nil
 }
 
-func (p *Parser) unexpected(t token.Token, ext string) error {
-	return fmt.Errorf("%s:%s: unexpected token - %#v(%s) %s", p.fileName, t.Location, t.Value, t.Tag, ext)
+func (p *Parser) errorf(loc *token.Location, code diag.Code, format string, args ...any) *diag.Diagnostic {
+	return &diag.Diagnostic{
+		FileName: p.fileName,
+		Loc:      *loc,
+		Severity: diag.Error,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
 }
 
+func (p *Parser) unexpected(t token.Token, ext string) *diag.Diagnostic {
+	return p.errorf(&t.Location, diag.UnexpectedToken, "unexpected token - %#v(%s) %s", t.Value, t.Tag, ext)
+}
+
+// invalidExpression reports d and returns the node standing in for the
+// expression it is about.
+func (p *Parser) invalidExpression(d *diag.Diagnostic) *ast.InvalidExpression {
+	p.addError(d)
+	p.broken = true
+	loc := d.Loc
+	return &ast.InvalidExpression{Loc: &loc, Err: d}
+}
+
+func (p *Parser) takeComments() []*ast.Comment {
+	cs := p.pending
+	p.pending = nil
+	return cs
+}
+
+// takeCommentsBehind takes the comments right behind the token just read,
+// such as an operator, before the operand following it is parsed.
+func (p *Parser) takeCommentsBehind() ([]*ast.Comment, error) {
+	if _, err := p.peekToken(); err != nil {
+		return nil, err
+	}
+	return p.takeComments(), nil
+}
+
+// attachTrailing attaches the pending comments that start on the last line
+// of n, which are those written after it on the same line.
+func (p *Parser) attachTrailing(n ast.Node) {
+	end := n.Location().EndLine
+	i := 0
+	for i < len(p.pending) && p.pending[i].Loc.StartLine == end {
+		i++
+	}
+	p.comments.AddTrailing(n, p.pending[:i]...)
+	p.pending = p.pending[i:]
+}
+
+// addError records err as a diagnostic; errors other than diagnostics,
+// such as read errors, only keep their message. Errors after the error
+// limit are dropped.
 func (p *Parser) addError(err error) {
-	p.errs = append(p.errs, err)
+	if p.stopped != nil {
+		return
+	}
+	d, ok := err.(*diag.Diagnostic)
+	if !ok {
+		d = &diag.Diagnostic{FileName: p.fileName, Severity: diag.Error, Message: err.Error()}
+	}
+	p.diags = append(p.diags, d)
+	if p.errorLimit > 0 && len(p.diags) >= p.errorLimit {
+		loc := d.Loc
+		p.stopped = &loc
+	}
 }
 
 func setLocation(loc *token.Location, beg *token.Location, end *token.Location) *token.Location {
@@ -79,10 +234,14 @@ func setLocation(loc *token.Location, beg *token.Location, end *token.Location)
 	if beg != nil {
 		loc.StartLine = beg.StartLine
 		loc.StartColumn = beg.StartColumn
+		loc.StartOffset = beg.StartOffset
+		loc.StartColumnUTF16 = beg.StartColumnUTF16
 	}
 	if end != nil {
 		loc.EndLine = end.EndLine
 		loc.EndColumn = end.EndColumn
+		loc.EndOffset = end.EndOffset
+		loc.EndColumnUTF16 = end.EndColumnUTF16
 	}
 	return loc
 }
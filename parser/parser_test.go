@@ -1,10 +1,16 @@
 package parser_test
 
 import (
+	"fmt"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/lexer"
 	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/token"
 )
 
 func TestParseIdentifier(t *testing.T) {
@@ -73,6 +79,41 @@ func TestParseFloatLiteral(t *testing.T) {
 	})
 }
 
+func TestParseNumberLiterals(t *testing.T) {
+	table := []struct {
+		src  string
+		want any
+	}{
+		{"0x1F", 31},
+		{"0XfF_fF", 65535},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"9223372036854775807", 9223372036854775807},
+		{"1e9", 1e9},
+		{"2.5e-3", 2.5e-3},
+		{"1_0.2_5", 10.25},
+		{"1E+2", 100.0},
+	}
+
+	for _, d := range table {
+		t.Run(d.src, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			testOneExpression(t, tree, func(t *testing.T, x ast.Expression) {
+				switch want := d.want.(type) {
+				case int:
+					testIntLiteral(t, x, want)
+				case float64:
+					testFloatLiteral(t, x, want)
+				}
+			})
+		})
+	}
+}
+
 func TestParseStringLiteral(t *testing.T) {
 	tree, err := parser.ParseString(`"Hello."`, "test.goore")
 	if err != nil {
@@ -84,6 +125,35 @@ func TestParseStringLiteral(t *testing.T) {
 	})
 }
 
+func TestParseInterpolatedString(t *testing.T) {
+	tree, err := parser.ParseString(`"a #{b + "c#{d}"} e"`, "test.goore")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	testOneExpression(t, tree, func(t *testing.T, x ast.Expression) {
+		want := `(interp (string "a ") (infix Add (ident b) (interp (string "c") (ident d))) (string " e"))`
+		if got := ast.Sexp(x); got != want {
+			t.Errorf("want <%v> got <%v>", want, got)
+		}
+		s := x.(*ast.InterpolatedString)
+		inner := s.Parts[1].(*ast.InfixExpression)
+		locs := []struct {
+			node ast.Node
+			want string
+		}{
+			{s, "(1:1):(1:20)"},
+			{inner, "(1:6):(1:16)"},
+			{inner.Right.(*ast.InterpolatedString).Parts[1], "(1:14):(1:14)"},
+		}
+		for _, l := range locs {
+			if got := l.node.Location().String(); got != l.want {
+				t.Errorf("want <%v> got <%v>", l.want, got)
+			}
+		}
+	})
+}
+
 func TestParsePrefixExpressions(t *testing.T) {
 	table := []struct {
 		name string
@@ -156,6 +226,48 @@ func TestParseInfixExpressions(t *testing.T) {
 	}
 }
 
+func TestParseSelfLet(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		op   ast.Operation
+	}{
+		{"LetAdd", `x += 2`, ast.Add},
+		{"LetSub", `x -= 2`, ast.Sub},
+		{"LetMul", `x *= 2`, ast.Mul},
+		{"LetDiv", `x /= 2`, ast.Div},
+		{"LetMod", `x %= 2`, ast.Mod},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			testOneExpression(t, tree, func(t *testing.T, x ast.Expression) {
+				let, ok := x.(*ast.Let)
+				if !ok {
+					t.Errorf("want <%T> got <%T>", &ast.Let{}, x)
+					return
+				}
+				testIdentifier(t, let.Left, "x")
+				right, ok := let.Right.(*ast.InfixExpression)
+				if !ok {
+					t.Errorf("want <%T> got <%T>", &ast.InfixExpression{}, let.Right)
+					return
+				}
+				if right.Operator != d.op {
+					t.Errorf("want <%v> got <%v>", d.op, right.Operator)
+				}
+				testIdentifier(t, right.Left, "x")
+				testIntLiteral(t, right.Right, 2)
+			})
+		})
+	}
+}
+
 func TestParseArrayLiterals(t *testing.T) {
 	table := []struct {
 		name string
@@ -181,6 +293,46 @@ func TestParseArrayLiterals(t *testing.T) {
 	}
 }
 
+func TestParseComments(t *testing.T) {
+	src := "# lead\nx # trail\n# next\ny\nwhile y { # inner\n}\n# end"
+	tree, err := parser.New(lexer.NewWithMode(strings.NewReader(src), lexer.ScanComments), "test.goore").Parse()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	pg := tree
+	if len(pg.Statements) != 3 {
+		t.Errorf("want <%d> got <%d>", 3, len(pg.Statements))
+		return
+	}
+	table := []struct {
+		name  string
+		node  ast.Node
+		which func(*ast.Comments) []*ast.Comment
+		want  []string
+	}{
+		{"leading", pg.Statements[0], func(c *ast.Comments) []*ast.Comment { return c.Leading }, []string{"# lead"}},
+		{"trailing", pg.Statements[0], func(c *ast.Comments) []*ast.Comment { return c.Trailing }, []string{"# trail"}},
+		{"next leading", pg.Statements[1], func(c *ast.Comments) []*ast.Comment { return c.Leading }, []string{"# next"}},
+		{"inner", pg.Statements[2], func(c *ast.Comments) []*ast.Comment { return c.Inner }, []string{"# inner"}},
+		{"last trailing", pg.Statements[2], func(c *ast.Comments) []*ast.Comment { return c.Trailing }, []string{"# end"}},
+	}
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			cs := d.which(pg.Comments.Get(d.node))
+			if len(cs) != len(d.want) {
+				t.Errorf("want <%d> got <%d>", len(d.want), len(cs))
+				return
+			}
+			for i, c := range cs {
+				if c.Text != d.want[i] {
+					t.Errorf("want <%v> got <%v>", d.want[i], c.Text)
+				}
+			}
+		})
+	}
+}
+
 func testArrayLiteral(t *testing.T, x ast.Expression, vals []int) {
 	a, ok := x.(*ast.ArrayLiteral)
 	if !ok {
@@ -318,3 +470,314 @@ func testInfixExpression(t *testing.T, expr ast.Expression, op ast.Operation, l
 	testIntLiteral(t, x.Left, l)
 	testIntLiteral(t, x.Right, r)
 }
+
+func TestParseUnclosedBlock(t *testing.T) {
+	// each used to loop forever on the EOF in place of the closing brace
+	table := []string{
+		"while x {\n1\n",
+		"while x {",
+		"if a { b } else {",
+		"-> (a) { a",
+	}
+
+	for _, src := range table {
+		t.Run(src, func(t *testing.T) {
+			tree, err := parser.ParseString(src, "test.goore")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if tree.Err == nil {
+				t.Error("want error got nil")
+			}
+		})
+	}
+}
+
+func TestParseDiagnostics(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+		code diag.Code
+		loc  string
+		fix  *diag.Fix
+	}{
+		{"unexpected token", "def 1", diag.UnexpectedToken, "(1:5):(1:5)", nil},
+		{"missing comma", "[1 2]", diag.MissingComma, "(1:4):(1:4)", &diag.Fix{
+			Message: "insert comma",
+			Loc:     token.Location{StartLine: 1, StartColumn: 2, EndLine: 1, EndColumn: 2},
+			Insert:  true,
+			NewText: ",",
+		}},
+		{"unclosed block", "while x {\n1\n", diag.UnclosedBlock, "(1:9):(3:1)", &diag.Fix{
+			Message: "close the block",
+			Loc:     token.Location{StartLine: 2, StartColumn: 1, EndLine: 2, EndColumn: 2},
+			Insert:  true,
+			NewText: "\n}",
+		}},
+		{"invalid assignment", "1 = 2", diag.InvalidAssignment, "(1:1):(1:1)", nil},
+		{"invalid character", "x $ y", diag.InvalidCharacter, "(1:3):(1:3)", nil},
+		{"invalid escape", `x = "a\qb"`, diag.InvalidEscape, "(1:7):(1:8)", nil},
+		{"malformed number", "x = 1__0", diag.InvalidNumber, "(1:6):(1:6)", nil},
+		{"integer out of range", "x = 9223372036854775808", diag.InvalidNumber, "(1:5):(1:23)", nil},
+		{"hex out of range", "x = 0x1_0000_0000_0000_0000", diag.InvalidNumber, "(1:5):(1:27)", nil},
+		{"float out of range", "x = 1e400", diag.InvalidNumber, "(1:5):(1:9)", nil},
+		{"for without variable", "for 1 in x {}", diag.UnexpectedToken, "(1:5):(1:5)", nil},
+		{"for without in", "for x y {}", diag.UnexpectedToken, "(1:7):(1:7)", nil},
+		{"break outside loop", "break", diag.JumpOutsideLoop, "(1:1):(1:5)", nil},
+		{"continue in function", "while x {\n  -> { continue }\n}", diag.JumpOutsideLoop, "(2:8):(2:15)", nil},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if len(tree.Diagnostics) == 0 {
+				t.Error("want diagnostics got none")
+				return
+			}
+			got := tree.Diagnostics[0]
+			if got.FileName != "test.goore" {
+				t.Errorf("want <%v> got <%v>", "test.goore", got.FileName)
+			}
+			if got.Code != d.code {
+				t.Errorf("want <%v> got <%v>", d.code, got.Code)
+			}
+			if got.Loc.String() != d.loc {
+				t.Errorf("want <%v> got <%v>", d.loc, got.Loc)
+			}
+			if fmt.Sprint(got.Fix) != fmt.Sprint(d.fix) {
+				t.Errorf("want <%v> got <%v>", d.fix, got.Fix)
+			}
+		})
+	}
+}
+
+func TestParseFileSet(t *testing.T) {
+	fset := token.NewFileSet()
+	a, err := parser.ParseFile(fset, "a.goore", "x = 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := parser.ParseFile(fset, "b.goore", "y = 2\ndef 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x := a.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Let).Left
+	y := b.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Let).Left
+	if a.Pos(x) == b.Pos(y) {
+		t.Errorf("want distinct positions got <%v> twice", a.Pos(x))
+	}
+	if got := fset.Position(b.Pos(y)).String(); got != "b.goore:1:1" {
+		t.Errorf("want <%v> got <%v>", "b.goore:1:1", got)
+	}
+	if got := fset.Position(a.Pos(x)).String(); got != "a.goore:1:1" {
+		t.Errorf("want <%v> got <%v>", "a.goore:1:1", got)
+	}
+
+	d := b.Diagnostics[0]
+	if got := fset.Position(b.File.Pos(d.Loc.StartOffset)).String(); got != "b.goore:2:5" {
+		t.Errorf("want <%v> got <%v>", "b.goore:2:5", got)
+	}
+}
+
+func TestParseRecovery(t *testing.T) {
+	table := []struct {
+		name  string
+		src   string
+		want  string
+		diags int
+	}{
+		{"error before closing brace", "while x { a = }\nc", "(program (while (ident x) (block (expr (let (ident a) (invalid-expr))))) (expr (ident c)))", 1},
+		{"error in function body", "def f = -> (a) {\n  a +* 1\n  return a\n}\nf(1)", "(program (def (ident f) (fn (params (ident a)) (block (expr (infix Add (ident a) (invalid-expr))) (return (ident a))))) (expr (call (ident f) (int 1))))", 1},
+		{"statement keyword on next line", "x = 1 +\nwhile y { z }", "(program (invalid) (while (ident y) (block (expr (ident z)))))", 1},
+		{"error in if body", "if a {\n  b c d\n} else {\n  e\n}", "(program (expr (if (ident a) (block (invalid)) (else (block (expr (ident e)))))))", 1},
+		{"braces inside skipped statement", "while x {\n  y = -> (1) { 2 }\n  z\n}", "(program (while (ident x) (block (invalid) (expr (ident z)))))", 1},
+		{"unclosed blocks keep their statements", "while x {\n  while y {\n    1\n", "(program (while (ident x) (block (while (ident y) (block (expr (int 1)))))))", 2},
+		{"stray brace", "}\nx", "(program (expr (invalid-expr)) (expr (ident x)))", 1},
+		{"invalid character", "x $ y\nz", "(program (invalid) (expr (ident z)))", 2},
+		{"invalid escapes keep the string", "x = \"a\\qb\\xffc\"\ny", "(program (expr (let (ident x) (string \"abc\"))) (expr (ident y)))", 2},
+		{"malformed numbers", "x = [0x, 1.]\ny = 1e400 + 2\nz", "(program (expr (let (ident x) (array (invalid-expr) (invalid-expr)))) (expr (let (ident y) (infix Add (invalid-expr) (int 2)))) (expr (ident z)))", 3},
+		{"error in interpolation", "x = \"#{1 2} a #{}\"\ny", "(program (expr (let (ident x) (interp (int 1) (string \" a \") (invalid-expr)))) (expr (ident y)))", 2},
+		{"unclosed interpolation", "x = \"#{1\n", "(program (invalid))", 1},
+		{"several errors", "a +* 1\nb\nc d\ne", "(program (expr (infix Add (ident a) (invalid-expr))) (expr (ident b)) (invalid) (expr (ident e)))", 2},
+	}
+
+	// the messages of invalid nodes are checked by TestParseDiagnostics
+	invalid := regexp.MustCompile(`\((invalid(-expr)?) "(\\.|[^"\\])*"\)`)
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := invalid.ReplaceAllString(ast.Sexp(tree), "($1)")
+			if got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+			if len(tree.Diagnostics) != d.diags {
+				t.Errorf("want <%d> diagnostics got <%v>", d.diags, tree.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestParsePartialExpression(t *testing.T) {
+	table := []struct {
+		name  string
+		src   string
+		want  string
+		diags int
+	}{
+		{"missing argument", "foo(1, , 3)", "(program (expr (call (ident foo) (int 1) (invalid-expr) (int 3))))", 1},
+		{"bad argument", "foo(1, (2 3), 4)", "(program (expr (call (ident foo) (int 1) (invalid-expr) (int 4))))", 1},
+		{"missing element", "[1, , 3]", "(program (expr (array (int 1) (invalid-expr) (int 3))))", 1},
+		{"missing operand", "[1 +]", "(program (expr (array (infix Add (int 1) (invalid-expr)))))", 1},
+		{"missing hash value", "{a: , b: 2}", "(program (expr (hash (entry (ident a) (invalid-expr)) (entry (ident b) (int 2)))))", 1},
+		{"missing colon", "{a 1}", "(program (expr (hash (entry (ident a) (int 1)))))", 1},
+		{"missing colon and value", "{a, b: 2}", "(program (expr (hash (entry (ident a) (invalid-expr)) (entry (ident b) (int 2)))))", 1},
+		{"unclosed call", "foo(1, 2", "(program (expr (call (ident foo) (int 1) (int 2) (invalid-expr))))", 1},
+		{"missing key", "a[]", "(program (expr (key-access (ident a) (invalid-expr))))", 1},
+		{"missing right side", "x = )\ny", "(program (expr (let (ident x) (invalid-expr))) (expr (ident y)))", 1},
+	}
+
+	invalid := regexp.MustCompile(`\(invalid-expr "(\\.|[^"\\])*"\)`)
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := invalid.ReplaceAllString(ast.Sexp(tree), "(invalid-expr)")
+			if got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+			if len(tree.Diagnostics) != d.diags {
+				t.Errorf("want <%d> diagnostics got <%v>", d.diags, tree.Diagnostics)
+			}
+		})
+	}
+
+	tree, err := parser.ParseString("foo(1, , 3)", "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := tree.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.Call).Arguments[1].(*ast.InvalidExpression)
+	want := token.Location{StartLine: 1, StartColumn: 8, EndLine: 1, EndColumn: 8, StartOffset: 7, EndOffset: 8, StartColumnUTF16: 8, EndColumnUTF16: 9}
+	if *x.Loc != want {
+		t.Errorf("want <%v> got <%v>", want, *x.Loc)
+	}
+	if x.Err != tree.Diagnostics[0] {
+		t.Errorf("want <%v> got <%v>", tree.Diagnostics[0], x.Err)
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	src := "x = [1 2 3]\ny = a +* 1\nz\nwhile q {\n  w\n  1 +"
+	table := []struct {
+		name  string
+		opts  []parser.Option
+		want  string
+		diags []string
+	}{
+		{
+			"default",
+			nil,
+			"(program (expr (let (ident x) (array (int 1) (int 2) (int 3)))) (expr (let (ident y) (infix Add (ident a) (invalid-expr)))) (expr (ident z)) (while (ident q) (block (expr (ident w)) (expr (infix Add (int 1) (invalid-expr))))))",
+			[]string{"missing comma", "missing comma", `"*"(Mul)`, `""(EOF)`, "unclosed block"},
+		},
+		{
+			"fail fast",
+			[]parser.Option{parser.FailFast()},
+			"(program)",
+			[]string{"missing comma"},
+		},
+		{
+			"error limit",
+			[]parser.Option{parser.ErrorLimit(3)},
+			"(program (expr (let (ident x) (array (int 1) (int 2) (int 3)))))",
+			[]string{"missing comma", "missing comma", `"*"(Mul)`},
+		},
+		{
+			"no limit",
+			[]parser.Option{parser.ErrorLimit(0)},
+			"(program (expr (let (ident x) (array (int 1) (int 2) (int 3)))) (expr (let (ident y) (infix Add (ident a) (invalid-expr)))) (expr (ident z)) (while (ident q) (block (expr (ident w)) (expr (infix Add (int 1) (invalid-expr))))))",
+			[]string{"missing comma", "missing comma", `"*"(Mul)`, `""(EOF)`, "unclosed block"},
+		},
+		{
+			"strict commas",
+			[]parser.Option{parser.StrictCommas()},
+			"(program (invalid) (expr (let (ident y) (infix Add (ident a) (invalid-expr)))) (expr (ident z)) (while (ident q) (block (expr (ident w)) (expr (infix Add (int 1) (invalid-expr))))))",
+			[]string{"missing comma", `"*"(Mul)`, `""(EOF)`, "unclosed block"},
+		},
+	}
+
+	invalid := regexp.MustCompile(`\((invalid(-expr)?) "(\\.|[^"\\])*"\)`)
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(src, "test.goore", d.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := invalid.ReplaceAllString(ast.Sexp(tree), "($1)")
+			if got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+			if len(tree.Diagnostics) != len(d.diags) {
+				t.Fatalf("want <%d> diagnostics got <%v>", len(d.diags), tree.Diagnostics)
+			}
+			for i, want := range d.diags {
+				if !strings.Contains(tree.Diagnostics[i].Message, want) {
+					t.Errorf("want <%v> got <%v>", want, tree.Diagnostics[i].Message)
+				}
+			}
+		})
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	table := []struct {
+		name   string
+		config parser.Config
+		src    string
+		diags  []diag.Code
+	}{
+		{"trailing commas", parser.Config{}, "f(1, 2,)\n[1,]\n{a: 1,}\na[1,]", nil},
+		{"no trailing commas", parser.Config{NoTrailingCommas: true}, "f(1, 2,)\n[1,]\n{a: 1,}\na[1,]", []diag.Code{diag.TrailingComma, diag.TrailingComma, diag.TrailingComma, diag.TrailingComma}},
+		{"no trailing commas without them", parser.Config{NoTrailingCommas: true}, "f(1, 2)\n[1,\n2]", nil},
+		{"semicolons", parser.Config{RequireSemicolons: true}, "def x = 1;\nwhile x { x -= 1; break; }\ndef f = -> { return }\nf();", nil},
+		{"missing semicolons", parser.Config{RequireSemicolons: true}, "def x\nx = 1\nwhile x {\n  continue\n  return\n}\nf()", []diag.Code{diag.MissingSemicolon, diag.MissingSemicolon, diag.MissingSemicolon, diag.MissingSemicolon}},
+		{"semicolon-free", parser.Config{}, "def x\nx = 1\nf()", nil},
+		{"if statement", parser.Config{Strict: true}, "if a { 1 } elsif b { if c { 2 } } else { 3 }", nil},
+		{"if value", parser.Config{Strict: true}, "x = if a { 1 } else { 2 }\nf(if a { 1 })\nif a { 1 } + 2\n-> { if a { 1 } }", []diag.Code{diag.IfAsValue, diag.IfAsValue, diag.IfAsValue}},
+		{"if value not strict", parser.Config{}, "x = if a { 1 } else { 2 }\nf(if a { 1 })", nil},
+		{"empty statements", parser.Config{}, ";;x;;", nil},
+		{"latest version", parser.Config{Version: parser.LatestVersion}, "x", nil},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			tree, err := parser.ParseString(d.src, "test.goore", parser.WithConfig(d.config))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tree.Diagnostics) != len(d.diags) {
+				t.Fatalf("want <%d> diagnostics got <%v>", len(d.diags), tree.Diagnostics)
+			}
+			for i, want := range d.diags {
+				if got := tree.Diagnostics[i].Code; got != want {
+					t.Errorf("want <%v> got <%v>", want, got)
+				}
+			}
+		})
+	}
+
+	_, err := parser.ParseString("x", "test.goore", parser.WithConfig(parser.Config{Version: parser.LatestVersion + 1}))
+	if err == nil {
+		t.Errorf("want error for unsupported version got <nil>")
+	}
+}
@@ -1,25 +1,32 @@
 package parser
 
 import (
-	"errors"
-
 	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/diag"
 	"github.com/arikui1911/goore/token"
 )
 
 func parseProgram(p *Parser) (*ast.Program, error) {
-	stmts, _, err := parseStatements(p, token.EOF)
+	stmts, _, err := parseStatements(p, token.EOF, nil)
 	if err != nil {
 		return nil, err
 	}
-	loc := &token.Location{StartLine: 1, StartColumn: 1, EndLine: 1, EndColumn: 1}
+	loc := &token.Location{StartLine: 1, StartColumn: 1, EndLine: 1, EndColumn: 1, StartColumnUTF16: 1, EndColumnUTF16: 1}
 	if len(stmts) > 0 {
 		setLocation(loc, stmts[0].Location(), stmts[len(stmts)-1].Location())
 	}
-	return &ast.Program{Loc: loc, FileName: p.fileName, Statements: stmts, Err: errors.Join(p.errs...)}, nil
+	prog := &ast.Program{Loc: loc, FileName: p.fileName, File: p.file, Statements: stmts, Comments: p.comments, Diagnostics: p.diags, Err: p.diags.Err()}
+	p.comments.AddInner(prog, p.takeComments()...)
+	return prog, nil
 }
 
-func parseStatements(p *Parser, term token.TokenTag) ([]ast.Statement, token.Token, error) {
+// parseStatements parses statements up to term; open is the brace that
+// began the block, if any.
+func parseStatements(p *Parser, term token.TokenTag, open *token.Token) ([]ast.Statement, token.Token, error) {
+	if open != nil {
+		p.blocks = append(p.blocks, p.braces)
+		defer func() { p.blocks = p.blocks[:len(p.blocks)-1] }()
+	}
 	buf := []ast.Statement{}
 	for {
 		t, err := p.nextToken()
@@ -27,16 +34,33 @@ func parseStatements(p *Parser, term token.TokenTag) ([]ast.Statement, token.Tok
 			return nil, token.Token{}, err
 		}
 		if t.Tag == term {
+			// comments before the terminator follow the last statement, or
+			// stay pending for the owner of an empty block
+			if len(buf) > 0 {
+				p.comments.AddTrailing(buf[len(buf)-1], p.takeComments()...)
+			}
+			return buf, t, nil
+		}
+		if t.Tag == token.EOF {
+			// keep what the block has so far; enclosing blocks meet the
+			// same end of file
+			p.addError(unclosedBlock(p, open, buf, t))
+			p.pushBack(t)
 			return buf, t, nil
 		}
 		p.pushBack(t)
+		leading := p.takeComments()
 		s, err := parseStatement(p)
 		if err != nil {
 			return nil, token.Token{}, err
 		}
-		if s != nil {
-			buf = append(buf, s)
+		if s == nil {
+			p.pending = append(leading, p.pending...)
+			continue
 		}
+		p.comments.AddLeading(s, leading...)
+		p.attachTrailing(s)
+		buf = append(buf, s)
 	}
 }
 
@@ -48,7 +72,19 @@ func parseBlock(p *Parser) ([]ast.Statement, token.Token, error) {
 	if t.Tag != token.LeftBrace {
 		return nil, token.Token{}, p.unexpected(t, "expect left brace to begin block")
 	}
-	return parseStatements(p, token.RightBrace)
+	return parseStatements(p, token.RightBrace, &t)
+}
+
+// unclosedBlock reports a block reaching the end of file, spanning from its
+// opening brace and suggesting the missing brace behind its contents.
+func unclosedBlock(p *Parser, open *token.Token, stmts []ast.Statement, eof token.Token) *diag.Diagnostic {
+	d := p.errorf(setLocation(nil, &open.Location, &eof.Location), diag.UnclosedBlock, "unclosed block - expect '}' before end of file")
+	last := &open.Location
+	if len(stmts) > 0 {
+		last = stmts[len(stmts)-1].Location()
+	}
+	d.Fix = &diag.Fix{Message: "close the block", Loc: *last, Insert: true, NewText: "\n}"}
+	return d
 }
 
 type statementParser func(*Parser) (ast.Statement, error)
@@ -60,6 +96,7 @@ func init() {
 		token.Semicolon: parseEmpty,
 		token.Def:       parseDef,
 		token.While:     parseWhile,
+		token.For:       parseFor,
 		token.Break:     parseBreak,
 		token.Continue:  parseContinue,
 		token.Return:    parseReturn,
@@ -75,7 +112,15 @@ func parseStatement(p *Parser) (ast.Statement, error) {
 	if !ok {
 		fn = parseExpressionStatement
 	}
+	// a function literal in the expression has statements of its own
+	broken := p.broken
+	p.broken = false
+	defer func() { p.broken = broken }()
 	s, err := fn(p)
+	if p.stopped != nil {
+		// cut off by the error limit
+		return nil, nil
+	}
 	if err != nil {
 		p.addError(err)
 		return parseInvalidStatement(p, t, err)
@@ -83,23 +128,120 @@ func parseStatement(p *Parser) (ast.Statement, error) {
 	return s, nil
 }
 
+var statementKeywords = map[token.TokenTag]bool{
+	token.Def:      true,
+	token.While:    true,
+	token.For:      true,
+	token.Break:    true,
+	token.Continue: true,
+	token.Return:   true,
+}
+
 func parseInvalidStatement(p *Parser, first token.Token, cause error) (*ast.InvalidStatement, error) {
+	last, err := skipStatement(p, first)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.InvalidStatement{
+		Loc: setLocation(nil, &first.Location, &last.Location),
+		Err: cause,
+	}, nil
+}
+
+// skipStatement skips the rest of a statement that failed to parse, up to
+// the end of its line, the '}' of the enclosing block or a statement
+// keyword beginning a line, leaving braces it opened balanced. It returns
+// the last token of the statement, last if it skips none.
+func skipStatement(p *Parser, last token.Token) (token.Token, error) {
+	base := 0
+	if len(p.blocks) > 0 {
+		base = p.blocks[len(p.blocks)-1]
+	}
 	for {
 		t, err := p.nextToken()
 		if err != nil {
-			return nil, err
+			return token.Token{}, err
 		}
-		if t.Tag != token.Newline && t.Tag != token.Semicolon && t.Tag != token.EOF {
-			continue
+		if t.Tag == token.EOF ||
+			(t.Tag == token.RightBrace && p.braces < base) ||
+			(statementKeywords[t.Tag] && p.braces == base && t.Location.StartLine > last.Location.EndLine) {
+			p.pushBack(t)
+			return last, nil
 		}
-		return &ast.InvalidStatement{
-			Loc: setLocation(nil, &first.Location, &t.Location),
-			Err: cause,
-		}, nil
+		if (t.Tag == token.Newline || t.Tag == token.Semicolon) && p.braces == base {
+			return t, nil
+		}
+		last = t
+	}
+}
+
+// terminate reads the newline or semicolon ending a statement with x.
+// Behind a broken expression, whose error is reported already, it skips to
+// the end of the statement instead.
+func terminate(p *Parser, x ast.Expression, ext string) (token.Token, error) {
+	t, err := p.nextToken()
+	if err != nil {
+		return token.Token{}, err
+	}
+	if t.Tag == token.Newline || t.Tag == token.Semicolon {
+		if !endsWithBlock(x) {
+			if err := checkSemicolon(p, t, x.Location()); err != nil {
+				return token.Token{}, err
+			}
+		}
+		return t, nil
+	}
+	if !p.broken {
+		return token.Token{}, p.unexpected(t, ext)
 	}
+	p.pushBack(t)
+	return skipStatement(p, token.Token{Location: *x.Location()})
 }
 
-func parseEmpty(*Parser) (ast.Statement, error) { return nil, nil }
+// checkSemicolon reports t, the token ending a statement behind last,
+// being a newline if the language requires semicolons. A newline before
+// the '}' closing a block is enough still.
+func checkSemicolon(p *Parser, t token.Token, last *token.Location) error {
+	if t.Tag != token.Newline || !p.config.RequireSemicolons {
+		return nil
+	}
+	nt, err := p.peekToken()
+	if err != nil {
+		return err
+	}
+	if nt.Tag == token.RightBrace {
+		return nil
+	}
+	d := p.errorf(&t.Location, diag.MissingSemicolon, "missing semicolon to terminate statement")
+	d.Fix = &diag.Fix{Message: "insert semicolon", Loc: *last, Insert: true, NewText: ";"}
+	p.addError(d)
+	return nil
+}
+
+// endsWithBlock tells whether x ends with the '}' of a block, behind which
+// a statement needs no semicolon.
+func endsWithBlock(x ast.Expression) bool {
+	switch x := x.(type) {
+	case *ast.If, *ast.Else, *ast.FunctionLiteral:
+		return true
+	case *ast.PrefixExpression:
+		return endsWithBlock(x.Right)
+	case *ast.InfixExpression:
+		return endsWithBlock(x.Right)
+	case *ast.LogicalExpression:
+		return endsWithBlock(x.Right)
+	case *ast.Let:
+		return endsWithBlock(x.Right)
+	case *ast.KeyAssign:
+		return endsWithBlock(x.Right)
+	}
+	return false
+}
+
+func parseEmpty(p *Parser) (ast.Statement, error) {
+	_, err := p.nextToken()
+	return nil, err
+}
 
 func parseDef(p *Parser) (ast.Statement, error) {
 	kw, err := p.nextToken()
@@ -116,6 +258,9 @@ func parseDef(p *Parser) (ast.Statement, error) {
 		return nil, err
 	}
 	if t.Tag == token.Newline || t.Tag == token.Semicolon {
+		if err := checkSemicolon(p, t, name.Loc); err != nil {
+			return nil, err
+		}
 		return &ast.Def{
 			Loc:  setLocation(nil, &kw.Location, &t.Location),
 			Name: name,
@@ -128,13 +273,10 @@ func parseDef(p *Parser) (ast.Statement, error) {
 	if err != nil {
 		return nil, err
 	}
-	t, err = p.nextToken()
+	t, err = terminate(p, x, "expect newline or semicolon to terminate def statement")
 	if err != nil {
 		return nil, err
 	}
-	if t.Tag != token.Newline && t.Tag != token.Semicolon {
-		return nil, p.unexpected(t, "expect newline or semicolon to terminate def statement")
-	}
 	return &ast.Def{
 		Loc:  setLocation(nil, &kw.Location, &t.Location),
 		Name: name,
@@ -151,10 +293,13 @@ func parseWhile(p *Parser) (ast.Statement, error) {
 	if err != nil {
 		return nil, err
 	}
+	p.loops++
 	stmts, rb, err := parseBlock(p)
+	p.loops--
 	if err != nil {
 		return nil, err
 	}
+	inner := p.takeComments()
 	t, err := p.nextToken()
 	if err != nil {
 		return nil, err
@@ -162,11 +307,68 @@ func parseWhile(p *Parser) (ast.Statement, error) {
 	if t.Tag != token.Newline {
 		p.pushBack(t)
 	}
-	return &ast.While{
+	s := &ast.While{
 		Loc:  setLocation(nil, &kw.Location, &rb.Location),
 		Cond: cond,
 		Body: stmts,
-	}, nil
+	}
+	p.comments.AddInner(s, inner...)
+	return s, nil
+}
+
+func parseFor(p *Parser) (ast.Statement, error) {
+	kw, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	var vars []*ast.Identifier
+	for {
+		t, err := p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		if t.Tag != token.Identifier {
+			return nil, p.unexpected(t, "expect identifier as loop variable")
+		}
+		vars = append(vars, &ast.Identifier{Loc: &t.Location, Name: t.Value})
+		t, err = p.nextToken()
+		if err != nil {
+			return nil, err
+		}
+		// in is a keyword only here
+		if t.Tag == token.Identifier && t.Value == "in" {
+			break
+		}
+		if t.Tag != token.Comma || len(vars) == 2 {
+			return nil, p.unexpected(t, "expect 'in'")
+		}
+	}
+	iterable, err := parseExpression(p, lowestPrecedence)
+	if err != nil {
+		return nil, err
+	}
+	p.loops++
+	stmts, rb, err := parseBlock(p)
+	p.loops--
+	if err != nil {
+		return nil, err
+	}
+	inner := p.takeComments()
+	t, err := p.nextToken()
+	if err != nil {
+		return nil, err
+	}
+	if t.Tag != token.Newline {
+		p.pushBack(t)
+	}
+	s := &ast.For{
+		Loc:      setLocation(nil, &kw.Location, &rb.Location),
+		Vars:     vars,
+		Iterable: iterable,
+		Body:     stmts,
+	}
+	p.comments.AddInner(s, inner...)
+	return s, nil
 }
 
 func parseBreak(p *Parser) (ast.Statement, error) {
@@ -181,6 +383,10 @@ func parseBreak(p *Parser) (ast.Statement, error) {
 	if t.Tag != token.Newline && t.Tag != token.Semicolon {
 		return nil, p.unexpected(t, "expect newline or semicolon to terminate break statement")
 	}
+	if err := checkSemicolon(p, t, &kw.Location); err != nil {
+		return nil, err
+	}
+	checkInLoop(p, kw)
 	return &ast.Break{Loc: setLocation(nil, &kw.Location, &t.Location)}, nil
 }
 
@@ -196,9 +402,21 @@ func parseContinue(p *Parser) (ast.Statement, error) {
 	if t.Tag != token.Newline && t.Tag != token.Semicolon {
 		return nil, p.unexpected(t, "expect newline or semicolon to terminate continue statement")
 	}
+	if err := checkSemicolon(p, t, &kw.Location); err != nil {
+		return nil, err
+	}
+	checkInLoop(p, kw)
 	return &ast.Continue{Loc: setLocation(nil, &kw.Location, &t.Location)}, nil
 }
 
+// checkInLoop reports the break or continue keyword kw outside of any loop
+// of the function it is in.
+func checkInLoop(p *Parser, kw token.Token) {
+	if p.loops == 0 {
+		p.addError(p.errorf(&kw.Location, diag.JumpOutsideLoop, "%s outside of loop", kw.Value))
+	}
+}
+
 func parseReturn(p *Parser) (ast.Statement, error) {
 	kw, err := p.nextToken()
 	if err != nil {
@@ -209,6 +427,9 @@ func parseReturn(p *Parser) (ast.Statement, error) {
 		return nil, err
 	}
 	if t.Tag == token.Newline || t.Tag == token.Semicolon {
+		if err := checkSemicolon(p, t, &kw.Location); err != nil {
+			return nil, err
+		}
 		return &ast.Return{Loc: setLocation(nil, &kw.Location, &t.Location)}, nil
 	}
 	p.pushBack(t)
@@ -216,27 +437,30 @@ func parseReturn(p *Parser) (ast.Statement, error) {
 	if err != nil {
 		return nil, err
 	}
-	t, err = p.nextToken()
+	t, err = terminate(p, x, "expect newline or semicolon to terminate return statement")
 	if err != nil {
 		return nil, err
 	}
-	if t.Tag != token.Newline && t.Tag != token.Semicolon {
-		return nil, p.unexpected(t, "expect newline or semicolon to terminate return statement")
-	}
 	return &ast.Return{Loc: setLocation(nil, &kw.Location, &t.Location), Expression: x}, nil
 }
 
 func parseExpressionStatement(p *Parser) (ast.Statement, error) {
-	x, err := parseExpression(p, lowestPrecedence)
+	first, err := p.peekToken()
 	if err != nil {
 		return nil, err
 	}
-	t, err := p.nextToken()
+	p.ifStatement = first.Tag == token.If
+	x, err := parseExpression(p, lowestPrecedence)
 	if err != nil {
 		return nil, err
 	}
-	if t.Tag != token.Semicolon && t.Tag != token.Newline {
-		return nil, p.unexpected(t, "to terminate expression statement")
+	if _, ok := x.(*ast.If); p.config.Strict && first.Tag == token.If && !ok {
+		// the if is an operand, as in `if a { 1 } + 2`
+		p.addError(ifAsValue(p, first))
+	}
+	t, err := terminate(p, x, "to terminate expression statement")
+	if err != nil {
+		return nil, err
 	}
 	return &ast.ExpressionStatement{Loc: setLocation(&token.Location{}, x.Location(), &t.Location), Expression: x}, nil
 }
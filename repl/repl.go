@@ -0,0 +1,91 @@
+package repl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/diag"
+	"github.com/arikui1911/goore/eval"
+	"github.com/arikui1911/goore/lexer"
+	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/token"
+)
+
+const (
+	Prompt             = ">> "
+	ContinuationPrompt = ".. "
+	fileName           = "<repl>"
+)
+
+// Start reads goore source from in line by line and evaluates each
+// complete input, writing results and errors to out. Definitions persist
+// across inputs. It returns when in is exhausted.
+func Start(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	interp := eval.New(out)
+	var buf strings.Builder
+
+	fmt.Fprint(out, Prompt)
+	for scanner.Scan() {
+		buf.WriteString(scanner.Text())
+		buf.WriteString("\n")
+		if incomplete(buf.String()) {
+			fmt.Fprint(out, ContinuationPrompt)
+			continue
+		}
+		evalInput(interp, buf.String(), out)
+		buf.Reset()
+		fmt.Fprint(out, Prompt)
+	}
+	fmt.Fprintln(out)
+	return scanner.Err()
+}
+
+func evalInput(interp *eval.Interpreter, src string, out io.Writer) {
+	tree, err := parser.ParseString(src, fileName)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return
+	}
+	if tree.Err != nil {
+		fmt.Fprintln(out, tree.Err)
+		return
+	}
+	for _, s := range tree.Statements {
+		v, err := interp.Run(&ast.Program{Loc: s.Location(), FileName: fileName, Statements: []ast.Statement{s}})
+		if err != nil {
+			fmt.Fprintln(out, err)
+			return
+		}
+		if _, ok := s.(*ast.ExpressionStatement); ok {
+			fmt.Fprintf(out, "=> %s\n", v.Inspect())
+		}
+	}
+}
+
+// incomplete reports whether src ends inside an unterminated string or
+// interpolation, or with an unclosed parenthesis, bracket or brace, so
+// more lines are needed before it can be parsed.
+func incomplete(src string) bool {
+	l := lexer.New(strings.NewReader(src))
+	depth := 0
+	for {
+		t, err := l.NextToken()
+		if err != nil {
+			d, ok := err.(*diag.Diagnostic)
+			return ok && d.Code == diag.UnterminatedString
+		}
+		switch t.Tag {
+		case token.EOF:
+			return depth > 0
+		case token.LeftParen, token.LeftBracket, token.LeftBrace, token.StringBegin:
+			depth++
+		case token.RightParen, token.RightBracket, token.RightBrace, token.StringEnd:
+			// a StringMiddle closes one interpolation but opens the next
+			depth--
+		}
+	}
+}
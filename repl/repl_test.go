@@ -0,0 +1,44 @@
+package repl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/arikui1911/goore/repl"
+)
+
+func TestStart(t *testing.T) {
+	table := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"expression", "1 + 2\n", ">> => 3\n>> \n"},
+		{"definitions persist", "def x = 1\nx += 1\nx\n", ">> >> => 2\n>> => 2\n>> \n"},
+		{"several statements", "def y = 2; y * 3; y\n", ">> => 6\n=> 2\n>> \n"},
+		{"block continuation", "def f = -> (a) {\n  a * 2\n}\nf(4)\n", ">> .. .. >> => 8\n>> \n"},
+		{"hash continuation", "{\n\"k\": 1\n}\n", ">> .. .. => {\"k\": 1}\n>> \n"},
+		{"paren continuation", "(1 +\n2)\n", ">> .. => 3\n>> \n"},
+		{"bracket continuation", "[1,\n2]\n", ">> .. => [1, 2]\n>> \n"},
+		{"string continuation", "\"a\nb\"\n", ">> .. => \"a\\nb\"\n>> \n"},
+		{"interpolation continuation", "\"a#{\n1 +\n2}b\"\n3\n", ">> .. .. => \"a3b\"\n>> => 3\n>> \n"},
+		{"interpolations", "\"#{1}-#{2}\"\n", ">> => \"1-2\"\n>> \n"},
+		{"puts", "puts(\"hi\")\n", ">> hi\n=> nil\n>> \n"},
+		{"runtime error", "z\n1\n", ">> <repl>:(1:1):(1:1): undefined variable - z\n>> => 1\n>> \n"},
+		{"syntax error", "def 1\n", ">> <repl>:(1:5):(1:5): unexpected token - \"1\"(IntLiteral) expect identifier\n>> \n"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := repl.Start(strings.NewReader(d.input), &out); err != nil {
+				t.Error(err)
+				return
+			}
+			if out.String() != d.want {
+				t.Errorf("want <%#v> got <%#v>", d.want, out.String())
+			}
+		})
+	}
+}
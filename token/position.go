@@ -0,0 +1,175 @@
+package token
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// Pos is a compact position in a FileSet: the base of a file plus a byte
+// offset into it. The zero value NoPos is no position at all.
+type Pos int
+
+const NoPos Pos = 0
+
+func (p Pos) IsValid() bool {
+	return p != NoPos
+}
+
+// Position is a Pos spelled out. Line and Column are one based, and Column
+// counts runes as Location does.
+type Position struct {
+	FileName string `json:"fileName"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns "file:line:col", leaving out what is unknown.
+func (p Position) String() string {
+	s := p.FileName
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// File is a source file registered in a FileSet.
+type File struct {
+	name  string
+	base  int
+	src   string
+	lines []int
+}
+
+func (f *File) Name() string {
+	return f.name
+}
+
+// Base returns the Pos of the first byte of f.
+func (f *File) Base() int {
+	return f.base
+}
+
+func (f *File) Size() int {
+	return len(f.src)
+}
+
+func (f *File) Source() string {
+	return f.src
+}
+
+func (f *File) LineCount() int {
+	return len(f.lines)
+}
+
+// Pos returns the Pos of the byte offset in f; offset may be the size of
+// f, for the end of its last token.
+func (f *File) Pos(offset int) Pos {
+	if offset < 0 || offset > len(f.src) {
+		panic(fmt.Sprintf("token: offset %d out of file %s of size %d", offset, f.name, len(f.src)))
+	}
+	return Pos(f.base + offset)
+}
+
+// Offset returns the byte offset of p in f.
+func (f *File) Offset(p Pos) int {
+	if int(p) < f.base || int(p) > f.base+len(f.src) {
+		panic(fmt.Sprintf("token: pos %d out of file %s", p, f.name))
+	}
+	return int(p) - f.base
+}
+
+// Position spells out p, which must be in f.
+func (f *File) Position(p Pos) Position {
+	offset := f.Offset(p)
+	i := sort.SearchInts(f.lines, offset+1) - 1
+	return Position{
+		FileName: f.name,
+		Offset:   offset,
+		Line:     i + 1,
+		Column:   utf8.RuneCountInString(f.src[f.lines[i]:offset]) + 1,
+	}
+}
+
+// Span returns the positions where loc, a location in f, begins and
+// ends; the end is behind its last character.
+func (f *File) Span(loc *Location) (Pos, Pos) {
+	return f.Pos(loc.StartOffset), f.Pos(loc.EndOffset)
+}
+
+// FileSet holds files parsed together so that a Pos tells both the file
+// and the place in it. It is safe for concurrent use.
+type FileSet struct {
+	mu    sync.RWMutex
+	base  int
+	files []*File
+}
+
+func NewFileSet() *FileSet {
+	return &FileSet{base: 1}
+}
+
+// Base returns the base the next added file will get.
+func (s *FileSet) Base() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.base
+}
+
+// AddFile registers a file with its source. Its positions follow those of
+// the files added before, with one to spare for its end.
+func (s *FileSet) AddFile(name string, src string) *File {
+	f := &File{name: name, src: src, lines: []int{0}}
+	for i, c := range src {
+		if c == '\n' {
+			f.lines = append(f.lines, i+1)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f.base = s.base
+	s.base += len(src) + 1
+	s.files = append(s.files, f)
+	return f
+}
+
+// File returns the file holding p, or nil.
+func (s *FileSet) File(p Pos) *File {
+	if !p.IsValid() {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	i := sort.Search(len(s.files), func(i int) bool { return s.files[i].base > int(p) }) - 1
+	if i < 0 || int(p) > s.files[i].base+len(s.files[i].src) {
+		return nil
+	}
+	return s.files[i]
+}
+
+// Position spells out p, or returns the zero Position if p is in no file.
+func (s *FileSet) Position(p Pos) Position {
+	if f := s.File(p); f != nil {
+		return f.Position(p)
+	}
+	return Position{}
+}
+
+// Files returns the files in the order they were added.
+func (s *FileSet) Files() []*File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]*File(nil), s.files...)
+}
@@ -0,0 +1,49 @@
+package token_test
+
+import (
+	"testing"
+
+	"github.com/arikui1911/goore/token"
+)
+
+func TestFileSet(t *testing.T) {
+	fset := token.NewFileSet()
+	a := fset.AddFile("a.goore", "x = 1\nputs(x)\n")
+	b := fset.AddFile("b.goore", "def ä = \"ü\"\n")
+
+	table := []struct {
+		name string
+		pos  token.Pos
+		want string
+	}{
+		{"first byte", a.Pos(0), "a.goore:1:1"},
+		{"second line", a.Pos(6), "a.goore:2:1"},
+		{"end of file", a.Pos(a.Size()), "a.goore:3:1"},
+		{"next file", b.Pos(0), "b.goore:1:1"},
+		{"rune column", b.Pos(10), "b.goore:1:10"},
+		{"no position", token.NoPos, "-"},
+		{"behind all files", token.Pos(fset.Base()), "-"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			got := fset.Position(d.pos).String()
+			if got != d.want {
+				t.Errorf("want <%v> got <%v>", d.want, got)
+			}
+		})
+	}
+
+	if a.Base()+a.Size() >= b.Base() {
+		t.Errorf("want <%d> before <%d>", a.Base()+a.Size(), b.Base())
+	}
+	if f := fset.File(b.Pos(3)); f != b {
+		t.Errorf("want <%v> got <%v>", b.Name(), f)
+	}
+	if got := b.Offset(b.Pos(5)); got != 5 {
+		t.Errorf("want <%d> got <%d>", 5, got)
+	}
+	if got := a.LineCount(); got != 3 {
+		t.Errorf("want <%d> got <%d>", 3, got)
+	}
+}
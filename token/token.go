@@ -2,11 +2,18 @@ package token
 
 import "fmt"
 
+// Location is the span of a token or node. Lines and columns are one
+// based and count runes, with the end column inclusive. Byte offsets and
+// UTF-16 columns, as LSP wants them, end behind the last character.
 type Location struct {
-	StartLine   int
-	StartColumn int
-	EndLine     int
-	EndColumn   int
+	StartLine        int `json:"startLine"`
+	StartColumn      int `json:"startColumn"`
+	EndLine          int `json:"endLine"`
+	EndColumn        int `json:"endColumn"`
+	StartOffset      int `json:"startOffset"`
+	EndOffset        int `json:"endOffset"`
+	StartColumnUTF16 int `json:"startColumnUTF16"`
+	EndColumnUTF16   int `json:"endColumnUTF16"`
 }
 
 func (l Location) String() string {
@@ -66,6 +73,20 @@ const (
 	Break
 	Continue
 	Return
+
+	// Later tags are appended here, so that the numbers of those above,
+	// which lexer.State saves, stay the same.
+	Comment
+	And
+	Or
+	For
+	// A string with interpolations is lexed as a StringBegin, up to the
+	// first "#{", the tokens of the embedded expression, a StringMiddle
+	// from its "}" up to the next "#{" if any, and so on, and a StringEnd
+	// from the last "}" on. Their values are the text between.
+	StringBegin
+	StringMiddle
+	StringEnd
 )
 
 type Token struct {
@@ -54,11 +54,18 @@ func _() {
 	_ = x[Break-43]
 	_ = x[Continue-44]
 	_ = x[Return-45]
+	_ = x[Comment-46]
+	_ = x[And-47]
+	_ = x[Or-48]
+	_ = x[For-49]
+	_ = x[StringBegin-50]
+	_ = x[StringMiddle-51]
+	_ = x[StringEnd-52]
 }
 
-const _TokenTag_name = "InvalidEOFIntLiteralFloatLiteralStringLiteralIdentifierEqNeLeGeLtGtAddSubMulDivModLetLetAddLetSubLetMulLetDivLetModBangArrowCommaColonSemicolonNewlineLeftParenRightParenLeftBraceRightBraceLeftBracketRightBracketTrueFalseNilDefIfElsifElseWhileBreakContinueReturn"
+const _TokenTag_name = "InvalidEOFIntLiteralFloatLiteralStringLiteralIdentifierEqNeLeGeLtGtAddSubMulDivModLetLetAddLetSubLetMulLetDivLetModBangArrowCommaColonSemicolonNewlineLeftParenRightParenLeftBraceRightBraceLeftBracketRightBracketTrueFalseNilDefIfElsifElseWhileBreakContinueReturnCommentAndOrForStringBeginStringMiddleStringEnd"
 
-var _TokenTag_index = [...]uint16{0, 7, 10, 20, 32, 45, 55, 57, 59, 61, 63, 65, 67, 70, 73, 76, 79, 82, 85, 91, 97, 103, 109, 115, 119, 124, 129, 134, 143, 150, 159, 169, 178, 188, 199, 211, 215, 220, 223, 226, 228, 233, 237, 242, 247, 255, 261}
+var _TokenTag_index = [...]uint16{0, 7, 10, 20, 32, 45, 55, 57, 59, 61, 63, 65, 67, 70, 73, 76, 79, 82, 85, 91, 97, 103, 109, 115, 119, 124, 129, 134, 143, 150, 159, 169, 178, 188, 199, 211, 215, 220, 223, 226, 228, 233, 237, 242, 247, 255, 261, 268, 271, 273, 276, 287, 299, 308}
 
 func (i TokenTag) String() string {
 	if i < 0 || i >= TokenTag(len(_TokenTag_index)-1) {
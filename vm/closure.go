@@ -0,0 +1,55 @@
+package vm
+
+import (
+	"fmt"
+
+	"github.com/arikui1911/goore/compiler"
+	"github.com/arikui1911/goore/object"
+)
+
+type Closure struct {
+	Fn       *compiler.CompiledFunction
+	Upvalues []*Upvalue
+}
+
+func (*Closure) Type() object.Type { return object.FunctionType }
+
+func (cl *Closure) String() string { return cl.Inspect() }
+
+func (cl *Closure) Inspect() string {
+	return fmt.Sprintf("#<function:%p>", cl)
+}
+
+// iterator is the state of a for loop, kept on the stack under the values
+// of its variables.
+type iterator struct {
+	it object.Iterator
+}
+
+func (*iterator) Type() object.Type { return "iterator" }
+
+func (i *iterator) String() string { return i.Inspect() }
+
+func (i *iterator) Inspect() string {
+	return fmt.Sprintf("#<iterator:%p>", i)
+}
+
+// Upvalue is a variable captured by a closure. While open it points into
+// the VM stack; once the variable goes out of scope it is closed and
+// points to its own copy.
+type Upvalue struct {
+	ref    *object.Object
+	closed object.Object
+	slot   int
+}
+
+func (u *Upvalue) close() {
+	u.closed = *u.ref
+	u.ref = &u.closed
+}
+
+type frame struct {
+	cl   *Closure
+	ip   int
+	base int
+}
@@ -0,0 +1,317 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/arikui1911/goore/ast"
+	"github.com/arikui1911/goore/compiler"
+	"github.com/arikui1911/goore/object"
+)
+
+const (
+	StackSize = 2048
+	MaxFrames = 1024
+)
+
+type VM struct {
+	fileName     string
+	constants    []object.Object
+	globals      []object.Object
+	globalNames  []string
+	stack        []object.Object
+	sp           int
+	frames       []*frame
+	openUpvalues []*Upvalue
+}
+
+func New(bc *compiler.Bytecode, out io.Writer) *VM {
+	vm := &VM{
+		fileName:    bc.FileName,
+		constants:   bc.Constants,
+		globals:     make([]object.Object, len(bc.Globals)),
+		globalNames: bc.Globals,
+		stack:       make([]object.Object, StackSize),
+	}
+	for i, b := range object.Builtins(out) {
+		vm.globals[i] = b
+	}
+	main := &Closure{Fn: bc.Main}
+	vm.frames = []*frame{{cl: main}}
+	vm.sp = main.Fn.NumLocals
+	for i := 0; i < vm.sp; i++ {
+		vm.stack[i] = object.Nil{}
+	}
+	return vm
+}
+
+func (vm *VM) errorf(f *frame, pos int, format string, args ...any) error {
+	return fmt.Errorf("%s:%s: %s", vm.fileName, f.cl.Fn.Locations[pos], fmt.Sprintf(format, args...))
+}
+
+func (vm *VM) push(f *frame, pos int, o object.Object) error {
+	if vm.sp >= StackSize {
+		return vm.errorf(f, pos, "stack overflow")
+	}
+	vm.stack[vm.sp] = o
+	vm.sp++
+	return nil
+}
+
+func (vm *VM) pop() object.Object {
+	vm.sp--
+	o := vm.stack[vm.sp]
+	vm.stack[vm.sp] = nil
+	return o
+}
+
+func (vm *VM) Run() (object.Object, error) {
+	for {
+		f := vm.frames[len(vm.frames)-1]
+		ins := f.cl.Fn.Instructions
+		pos := f.ip
+		op := compiler.Opcode(ins[pos])
+		f.ip++
+
+		var err error
+		switch op {
+		case compiler.OpConstant:
+			err = vm.push(f, pos, vm.constants[compiler.ReadUint16(ins, f.ip)])
+			f.ip += 2
+		case compiler.OpNil:
+			err = vm.push(f, pos, object.Nil{})
+		case compiler.OpTrue:
+			err = vm.push(f, pos, object.Bool(true))
+		case compiler.OpFalse:
+			err = vm.push(f, pos, object.Bool(false))
+		case compiler.OpPop:
+			vm.pop()
+		case compiler.OpPrefix:
+			o, perr := object.Prefix(astOperation(ins, f.ip), vm.pop())
+			f.ip++
+			if perr != nil {
+				return nil, vm.errorf(f, pos, "%v", perr)
+			}
+			err = vm.push(f, pos, o)
+		case compiler.OpInfix:
+			r := vm.pop()
+			l := vm.pop()
+			o, ierr := object.Infix(astOperation(ins, f.ip), l, r)
+			f.ip++
+			if ierr != nil {
+				return nil, vm.errorf(f, pos, "%v", ierr)
+			}
+			err = vm.push(f, pos, o)
+		case compiler.OpJump:
+			f.ip = compiler.ReadUint16(ins, f.ip)
+		case compiler.OpJumpIfFalse:
+			if object.Truthy(vm.pop()) {
+				f.ip += 2
+			} else {
+				f.ip = compiler.ReadUint16(ins, f.ip)
+			}
+		case compiler.OpJumpIfFalseOrPop, compiler.OpJumpIfTrueOrPop:
+			if object.Truthy(vm.stack[vm.sp-1]) == (op == compiler.OpJumpIfTrueOrPop) {
+				f.ip = compiler.ReadUint16(ins, f.ip)
+			} else {
+				vm.pop()
+				f.ip += 2
+			}
+		case compiler.OpGetGlobal:
+			i := compiler.ReadUint16(ins, f.ip)
+			f.ip += 2
+			if vm.globals[i] == nil {
+				return nil, vm.errorf(f, pos, "undefined variable - %s", vm.globalNames[i])
+			}
+			err = vm.push(f, pos, vm.globals[i])
+		case compiler.OpSetGlobal:
+			i := compiler.ReadUint16(ins, f.ip)
+			f.ip += 2
+			if vm.globals[i] == nil {
+				return nil, vm.errorf(f, pos, "undefined variable - %s", vm.globalNames[i])
+			}
+			vm.globals[i] = vm.stack[vm.sp-1]
+		case compiler.OpDefineGlobal:
+			vm.globals[compiler.ReadUint16(ins, f.ip)] = vm.pop()
+			f.ip += 2
+		case compiler.OpGetLocal:
+			err = vm.push(f, pos, vm.stack[f.base+compiler.ReadUint8(ins, f.ip)])
+			f.ip++
+		case compiler.OpSetLocal:
+			vm.stack[f.base+compiler.ReadUint8(ins, f.ip)] = vm.stack[vm.sp-1]
+			f.ip++
+		case compiler.OpGetUpvalue:
+			err = vm.push(f, pos, *f.cl.Upvalues[compiler.ReadUint8(ins, f.ip)].ref)
+			f.ip++
+		case compiler.OpSetUpvalue:
+			*f.cl.Upvalues[compiler.ReadUint8(ins, f.ip)].ref = vm.stack[vm.sp-1]
+			f.ip++
+		case compiler.OpCloseUpvalues:
+			vm.closeUpvalues(f.base + compiler.ReadUint8(ins, f.ip))
+			f.ip++
+		case compiler.OpArray:
+			n := compiler.ReadUint16(ins, f.ip)
+			f.ip += 2
+			elems := make([]object.Object, n)
+			copy(elems, vm.stack[vm.sp-n:vm.sp])
+			vm.sp -= n
+			err = vm.push(f, pos, &object.Array{Elements: elems})
+		case compiler.OpConcat:
+			n := compiler.ReadUint16(ins, f.ip)
+			f.ip += 2
+			s := object.Concat(vm.stack[vm.sp-n : vm.sp])
+			vm.sp -= n
+			err = vm.push(f, pos, s)
+		case compiler.OpHash:
+			n := compiler.ReadUint16(ins, f.ip)
+			f.ip += 2
+			h := object.NewHash()
+			for i := vm.sp - 2*n; i < vm.sp; i += 2 {
+				k, ok := vm.stack[i].(object.Hashable)
+				if !ok {
+					return nil, vm.errorf(f, pos, "unhashable key - %s", vm.stack[i].Type())
+				}
+				h.Set(k, vm.stack[i+1])
+			}
+			vm.sp -= 2 * n
+			err = vm.push(f, pos, h)
+		case compiler.OpIndex:
+			k := vm.pop()
+			c := vm.pop()
+			o, ierr := object.Index(c, k)
+			if ierr != nil {
+				return nil, vm.errorf(f, pos, "%v", ierr)
+			}
+			err = vm.push(f, pos, o)
+		case compiler.OpSetIndex:
+			v := vm.pop()
+			k := vm.pop()
+			c := vm.pop()
+			if serr := object.SetIndex(c, k, v); serr != nil {
+				return nil, vm.errorf(f, pos, "%v", serr)
+			}
+			err = vm.push(f, pos, v)
+		case compiler.OpCall:
+			argc := compiler.ReadUint8(ins, f.ip)
+			f.ip++
+			err = vm.call(f, pos, argc)
+		case compiler.OpReturn:
+			result := vm.pop()
+			if len(vm.frames) == 1 {
+				return result, nil
+			}
+			vm.closeUpvalues(f.base)
+			for vm.sp > f.base-1 {
+				vm.pop()
+			}
+			vm.frames = vm.frames[:len(vm.frames)-1]
+			err = vm.push(f, pos, result)
+		case compiler.OpClosure:
+			fn := vm.constants[compiler.ReadUint16(ins, f.ip)].(*compiler.CompiledFunction)
+			n := compiler.ReadUint8(ins, f.ip+2)
+			f.ip += 3
+			cl := &Closure{Fn: fn, Upvalues: make([]*Upvalue, n)}
+			for i := range cl.Upvalues {
+				isLocal := ins[f.ip] == 1
+				index := int(ins[f.ip+1])
+				f.ip += 2
+				if isLocal {
+					cl.Upvalues[i] = vm.captureUpvalue(f.base + index)
+				} else {
+					cl.Upvalues[i] = f.cl.Upvalues[index]
+				}
+			}
+			err = vm.push(f, pos, cl)
+		case compiler.OpIter:
+			n := compiler.ReadUint8(ins, f.ip)
+			f.ip++
+			x := vm.pop()
+			it, ok := x.(object.Iterable)
+			if !ok {
+				return nil, vm.errorf(f, pos, "not iterable - %s", x.Type())
+			}
+			err = vm.push(f, pos, &iterator{it.Iterate(n == 2)})
+		case compiler.OpIterNext:
+			n := compiler.ReadUint8(ins, f.ip+2)
+			k, v, ok := vm.stack[vm.sp-1].(*iterator).it.Next()
+			if !ok {
+				vm.pop()
+				f.ip = compiler.ReadUint16(ins, f.ip)
+				break
+			}
+			f.ip += 3
+			if n == 2 {
+				if err = vm.push(f, pos, k); err != nil {
+					break
+				}
+			}
+			err = vm.push(f, pos, v)
+		default:
+			return nil, vm.errorf(f, pos, "unknown opcode - %v", op)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func astOperation(ins compiler.Instructions, offset int) ast.Operation {
+	return ast.Operation(compiler.ReadUint8(ins, offset))
+}
+
+func (vm *VM) call(f *frame, pos int, argc int) error {
+	callee := vm.stack[vm.sp-1-argc]
+	switch fn := callee.(type) {
+	case *Closure:
+		if argc != fn.Fn.NumParams {
+			return vm.errorf(f, pos, "wrong number of arguments (given %d, expected %d)", argc, fn.Fn.NumParams)
+		}
+		if len(vm.frames) >= MaxFrames {
+			return vm.errorf(f, pos, "stack overflow")
+		}
+		base := vm.sp - argc
+		if base+fn.Fn.NumLocals >= StackSize {
+			return vm.errorf(f, pos, "stack overflow")
+		}
+		for vm.sp < base+fn.Fn.NumLocals {
+			vm.stack[vm.sp] = object.Nil{}
+			vm.sp++
+		}
+		vm.frames = append(vm.frames, &frame{cl: fn, base: base})
+		return nil
+	case *object.Builtin:
+		args := make([]object.Object, argc)
+		copy(args, vm.stack[vm.sp-argc:vm.sp])
+		v, err := fn.Fn(args)
+		if err != nil {
+			return vm.errorf(f, pos, "%s: %v", fn.Name, err)
+		}
+		vm.sp -= argc + 1
+		return vm.push(f, pos, v)
+	default:
+		return vm.errorf(f, pos, "not callable - %s", callee.Type())
+	}
+}
+
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	for _, u := range vm.openUpvalues {
+		if u.slot == slot {
+			return u
+		}
+	}
+	u := &Upvalue{ref: &vm.stack[slot], slot: slot}
+	vm.openUpvalues = append(vm.openUpvalues, u)
+	return u
+}
+
+func (vm *VM) closeUpvalues(from int) {
+	open := vm.openUpvalues[:0]
+	for _, u := range vm.openUpvalues {
+		if u.slot >= from {
+			u.close()
+		} else {
+			open = append(open, u)
+		}
+	}
+	vm.openUpvalues = open
+}
@@ -0,0 +1,119 @@
+package vm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/arikui1911/goore/compiler"
+	"github.com/arikui1911/goore/eval"
+	"github.com/arikui1911/goore/parser"
+	"github.com/arikui1911/goore/vm"
+)
+
+type result struct {
+	value string
+	out   string
+	err   string
+}
+
+func runEval(t *testing.T, src string) result {
+	tree, err := parser.ParseString(src, "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	v, err := eval.New(&out).Run(tree)
+	return newResult(v, out.String(), err)
+}
+
+func runVM(t *testing.T, src string) result {
+	tree, err := parser.ParseString(src, "test.goore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc, err := compiler.Compile(tree)
+	if err != nil {
+		return newResult(nil, "", err)
+	}
+	var out bytes.Buffer
+	v, err := vm.New(bc, &out).Run()
+	return newResult(v, out.String(), err)
+}
+
+func newResult(v interface{ Inspect() string }, out string, err error) result {
+	r := result{out: out}
+	if err != nil {
+		r.err = err.Error()
+	} else {
+		r.value = v.Inspect()
+	}
+	return r
+}
+
+func TestVMMatchesEval(t *testing.T) {
+	table := []struct {
+		name string
+		src  string
+	}{
+		{"literals", `[nil, true, false, 1, 2.5, "s", {"k": [1]}]`},
+		{"arithmetic", `1 + 2 * 3 - 4 / 2 + 7 % 3`},
+		{"prefix", `[-1, +2.5, !nil, !0]`},
+		{"number forms", `[0x1F, 0o17, 0b1010, 1_000_000, 1e9, 2.5e-3]`},
+		{"comparison", `[1 < 2, 2 <= 1, "a" < "b", 1 == 1.0, [1] != [1]]`},
+		{"logical", `[true && 1, nil && 1, false || 2, 3 || x, nil || false, 1 < 2 && 2 < 3]`},
+		{"short circuit", "def n = 0\ndef inc = -> { n += 1 }\nfalse && inc()\ntrue || inc()\ntrue && inc()\nnil || inc()\nn"},
+		{"globals", "def x = 1\nx += 2\nx"},
+		{"def without init", "def x\nx"},
+		{"if", "def f = -> (n) {\n  if n < 0 { \"neg\" } elsif n == 0 { \"zero\" } else { \"pos\" }\n}\n[f(-1), f(0), f(1)]"},
+		{"if without else", `if false { 1 }`},
+		{"while", "def i = 0\ndef s = 0\nwhile i < 100 {\n  i += 1\n  s += i\n}\ns"},
+		{"break and continue", "def i = 0\ndef s = 0\nwhile true {\n  i += 1\n  if i > 10 { break }\n  if i % 2 == 0 { continue }\n  s += i\n}\ns"},
+		{"deepest recursion", "def f = -> (n) { if n < 1022 { f(n + 1) } else { n } }\nf(0)"},
+		{"break inside expression", "def i = 0\nwhile true {\n  i = i + if i == 5 { break } else { 1 }\n}\ni"},
+		{"nested loops", "def out = []\ndef i = 0\nwhile i < 3 {\n  def j = 0\n  while true {\n    j += 1\n    if j > i { break }\n    push(out, [i, j])\n  }\n  i += 1\n}\nout"},
+		{"block scope", "def x = 1\nif true {\n  def x = 2\n  x += 1\n}\nx"},
+		{"function", "def add = -> (a, b) { a + b }\nadd(1, 2)"},
+		{"implicit return", "(-> { 1\n 2 })()"},
+		{"return", "def f = -> (x) {\n  while true {\n    if x > 3 { return x }\n    x += 1\n  }\n}\nf(0)"},
+		{"recursion", "def fib = -> (n) {\n  if n < 2 { return n }\n  fib(n - 1) + fib(n - 2)\n}\nfib(15)"},
+		{"local recursion", "def f = -> {\n  def fact = -> (n) { if n < 2 { 1 } else { n * fact(n - 1) } }\n  fact(10)\n}\nf()"},
+		{"closure counter", "def counter = -> {\n  def n = 0\n  -> { n += 1 }\n}\ndef c = counter()\nc()\nc()\n[c(), counter()()]"},
+		{"shared upvalue", "def make = -> {\n  def n = 0\n  [-> { n += 1 }, -> { n }]\n}\ndef fs = make()\nfs[0]()\nfs[0]()\nfs[1]()"},
+		{"nested closure", "def f = -> (a) { -> (b) { -> (c) { a + b + c } } }\nf(1)(2)(3)"},
+		{"closure per iteration", "def fs = []\ndef i = 0\nwhile i < 3 {\n  def j = i\n  push(fs, -> { j })\n  i += 1\n}\n[fs[0](), fs[1](), fs[2]()]"},
+		{"closure with break", "def fs = []\ndef i = 0\nwhile true {\n  def j = i\n  push(fs, -> { j })\n  if i == 2 { break }\n  i += 1\n}\n[fs[0](), fs[2]()]"},
+		{"interpolation", "def name = \"bob\"\ndef n = 2\n\"hello #{name}, #{n + 1} #{\"x#{[nil, 1.5]}\"}\""},
+		{"for", "def s = []\nfor i, x in [\"a\", \"b\"] {\n  push(s, [i, x])\n}\nfor k, v in {1: 2, 3: 4} {\n  push(s, k + v)\n}\nfor c in \"ab\" {\n  push(s, c)\n}\nfor i in range(5, 0, -2) {\n  push(s, i)\n}\ns"},
+		{"for break and continue", "def s = 0\nfor i in range(10) {\n  if i == 5 { break }\n  if i % 2 == 0 { continue }\n  s += i\n}\ns"},
+		{"nested for", "def f = -> (xs) {\n  def n = 0\n  for x in xs {\n    for y in xs {\n      if y > x { break }\n      n += y\n    }\n    if x == 3 { return n }\n  }\n  n\n}\n[f([1, 2, 3, 4]), f([1, 2])]"},
+		{"for closures", "def fs = []\nfor i in range(3) {\n  push(fs, -> { i })\n  if i == 1 { break }\n}\n[fs[0](), fs[1]()]"},
+		{"not iterable", `for x in nil { x }`},
+		{"key assign", "def a = [1, 2, 3]\ndef h = {}\na[1] *= 10\nh[\"k\"] = a\nh"},
+		{"builtins", "puts(\"hello\", 1, [2])\nprint(len(\"abc\"), type(1.5))\nkeys({1: 2, \"a\": 3})"},
+		{"top level return", "def x = 1\nreturn x + 1\nputs(\"unreachable\")"},
+		{"undefined variable", `foo`},
+		{"undefined let", `foo = 1`},
+		{"type error", `1 + "a"`},
+		{"division by zero", `1 % 0`},
+		{"not callable", `"a"()`},
+		{"arity", `(-> (a) { a })(1, 2)`},
+		{"builtin error", `len(1)`},
+		{"unhashable", `{[]: 1}`},
+		{"index error", `[1][1] = 2`},
+		{"break in function", "while true {\n  (-> { break })()\n}"},
+		{"continue in uncalled function", "while true {\n  -> { continue }\n  break\n}"},
+		{"mutually recursive locals", "def f = -> {\n  def even = -> (n) { if n == 0 { true } else { odd(n - 1) } }\n  def odd = -> (n) { if n == 0 { false } else { even(n - 1) } }\n  even(4)\n}\nputs(f())"},
+		{"outer variable before def", "def x = 1\ndef f = -> {\n  def y = x\n  def x = y + 1\n  [y, x]\n}\nf()"},
+		{"loop in function", "def f = -> {\n  while true { break }\n  1\n}\nwhile true {\n  f()\n  break\n}"},
+	}
+
+	for _, d := range table {
+		t.Run(d.name, func(t *testing.T) {
+			want := runEval(t, d.src)
+			got := runVM(t, d.src)
+			if got != want {
+				t.Errorf("want <%#v> got <%#v>", want, got)
+			}
+		})
+	}
+}